@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// hnBaseURL is the Hacker News site root; feed and "More" links are resolved
+// against it
+const hnBaseURL = "https://news.ycombinator.com/"
+
+// DefaultFeed is the feed fetched when the request names none
+const DefaultFeed = "news"
+
+// feedPaths maps each feed name to the path of its first page
+var feedPaths = map[string]string{
+	"news":   "",
+	"newest": "newest",
+	"best":   "best",
+	"ask":    "ask",
+	"show":   "show",
+	"jobs":   "jobs",
+	"front":  "front",
+}
+
+// Feed identifies a Hacker News listing: one of the feedPaths names, and for
+// "front" the day whose front page to fetch
+type Feed struct {
+	Name string
+	Day  string
+}
+
+// feedNames returns the accepted feed names, sorted
+func feedNames() []string {
+	names := make([]string, 0, len(feedPaths))
+	for name := range feedPaths {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseFeed validates a feed name and day from a request. An empty name
+// means DefaultFeed. The day, in YYYY-MM-DD form, is only accepted for
+// "front"; without it HN shows the previous day's front page.
+func parseFeed(name, day string) (Feed, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		name = DefaultFeed
+	}
+
+	// Also accept the URL form, e.g. "front?day=2025-12-05"
+	if path, query, ok := strings.Cut(name, "?"); ok {
+		values, err := url.ParseQuery(query)
+		if err != nil {
+			return Feed{}, fmt.Errorf("invalid feed %q", name)
+		}
+		if day == "" {
+			day = values.Get("day")
+		}
+		name = path
+	}
+
+	if _, ok := feedPaths[name]; !ok {
+		return Feed{}, fmt.Errorf("unknown feed %q (expected one of %s)", name, strings.Join(feedNames(), ", "))
+	}
+
+	day = strings.TrimSpace(day)
+	if day != "" {
+		if name != "front" {
+			return Feed{}, fmt.Errorf("day is only supported for the front feed")
+		}
+		if _, err := time.Parse("2006-01-02", day); err != nil {
+			return Feed{}, fmt.Errorf("invalid day %q (expected YYYY-MM-DD)", day)
+		}
+	}
+
+	return Feed{Name: name, Day: day}, nil
+}
+
+// firstPageURL returns the URL of the feed's first page
+func (f Feed) firstPageURL() string {
+	u := hnBaseURL + feedPaths[f.Name]
+	if f.Day != "" {
+		u += "?day=" + f.Day
+	}
+	return u
+}
+
+// nextPageURL resolves the href of a page's "More" link against the page's
+// URL. Each feed paginates differently (?p=2 for news, best, ask, show and
+// front, ?next=<id> for newest and jobs), so the link is followed as is.
+func nextPageURL(pageURL, more string) (string, error) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid page URL: %w", err)
+	}
+	ref, err := url.Parse(more)
+	if err != nil {
+		return "", fmt.Errorf("invalid More link %q: %w", more, err)
+	}
+	return base.ResolveReference(ref).String(), nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import "testing"
+
+func TestParseFeed(t *testing.T) {
+	tests := []struct {
+		name    string
+		feed    string
+		day     string
+		want    Feed
+		wantURL string
+		wantErr bool
+	}{
+		{"default", "", "", Feed{Name: "news"}, "https://news.ycombinator.com/", false},
+		{"newest", "newest", "", Feed{Name: "newest"}, "https://news.ycombinator.com/newest", false},
+		{"case and spaces", " Show ", "", Feed{Name: "show"}, "https://news.ycombinator.com/show", false},
+		{"front without day", "front", "", Feed{Name: "front"}, "https://news.ycombinator.com/front", false},
+		{"front with day", "front", "2025-12-05", Feed{Name: "front", Day: "2025-12-05"}, "https://news.ycombinator.com/front?day=2025-12-05", false},
+		{"url form", "front?day=2025-12-05", "", Feed{Name: "front", Day: "2025-12-05"}, "https://news.ycombinator.com/front?day=2025-12-05", false},
+		{"day field wins", "front?day=2025-12-05", "2025-12-01", Feed{Name: "front", Day: "2025-12-01"}, "https://news.ycombinator.com/front?day=2025-12-01", false},
+		{"unknown feed", "popular", "", Feed{}, "", true},
+		{"day on other feed", "news", "2025-12-05", Feed{}, "", true},
+		{"invalid day", "front", "05/12/2025", Feed{}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseFeed(tt.feed, tt.day)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseFeed(%q, %q) error = %v, want error %v", tt.feed, tt.day, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got != tt.want {
+				t.Errorf("parseFeed(%q, %q) = %+v, want %+v", tt.feed, tt.day, got, tt.want)
+			}
+			if u := got.firstPageURL(); u != tt.wantURL {
+				t.Errorf("firstPageURL() = %q, want %q", u, tt.wantURL)
+			}
+		})
+	}
+}
+
+func TestNextPageURL(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+		more string
+		want string
+	}{
+		{"news", "https://news.ycombinator.com/", "?p=2", "https://news.ycombinator.com/?p=2"},
+		{"news relative path", "https://news.ycombinator.com/", "news?p=2", "https://news.ycombinator.com/news?p=2"},
+		{"newest", "https://news.ycombinator.com/newest", "newest?next=46170099&n=31", "https://news.ycombinator.com/newest?next=46170099&n=31"},
+		{"front keeps day", "https://news.ycombinator.com/front?day=2025-12-05", "front?day=2025-12-05&p=2", "https://news.ycombinator.com/front?day=2025-12-05&p=2"},
+		{"absolute", "https://news.ycombinator.com/jobs", "https://news.ycombinator.com/jobs?next=1", "https://news.ycombinator.com/jobs?next=1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := nextPageURL(tt.page, tt.more)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("nextPageURL(%q, %q) = %q, want %q", tt.page, tt.more, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHNPageMoreLink(t *testing.T) {
+	stories, more, err := ParseHNPage(readPage(t, "newest.html"), 1, fetchedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stories) != 2 {
+		t.Fatalf("parsed %d stories, want 2", len(stories))
+	}
+	if more != "newest?next=46170099&n=31" {
+		t.Errorf("More link = %q", more)
+	}
+	next, _ := nextPageURL("https://news.ycombinator.com/newest", more)
+	if next != "https://news.ycombinator.com/newest?next=46170099&n=31" {
+		t.Errorf("next page = %q", next)
+	}
+}
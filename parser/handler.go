@@ -2,8 +2,12 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
+	"time"
 )
 
 // Handler holds the dependencies for HTTP handlers
@@ -27,13 +31,25 @@ func (h *Handler) HandleFetch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The body is optional; without one the front page is fetched
+	var req FetchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	feed, err := parseFeed(req.Feed, req.Day)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	var allStories []Story
 	var firstFetchedAt string
+	numPages := 0
 
-	// Fetch each page sequentially
+	// Fetch each page sequentially, following the "More" link
+	url := feed.firstPageURL()
 	for page := 1; page <= h.numPages; page++ {
-		url := buildHNURL(page)
-
 		resp, err := h.rateLimiter.FetchURL(url)
 		if err != nil {
 			writeError(w, http.StatusBadGateway, fmt.Sprintf("Failed to fetch page %d: %v", page, err))
@@ -45,20 +61,37 @@ func (h *Handler) HandleFetch(w http.ResponseWriter, r *http.Request) {
 			firstFetchedAt = resp.FetchedAt
 		}
 
-		// Parse the HTML
-		stories, err := ParseHNPage(resp.HTML, page)
+		// Parse the HTML; ages are relative to when the page was fetched
+		fetchedAt, err := time.Parse(time.RFC3339, resp.FetchedAt)
+		if err != nil {
+			fetchedAt = time.Now()
+		}
+		stories, more, err := ParseHNPage(resp.HTML, page, fetchedAt)
 		if err != nil {
 			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to parse page %d: %v", page, err))
 			return
 		}
 
 		allStories = append(allStories, stories...)
+		numPages = page
+
+		// Stop early if this was the feed's last page
+		if more == "" {
+			break
+		}
+		url, err = nextPageURL(url, more)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to parse page %d: %v", page, err))
+			return
+		}
 	}
 
 	// Build response
 	response := FetchResponse{
+		Feed:         feed.Name,
+		Day:          feed.Day,
 		FetchedAt:    firstFetchedAt,
-		NumPages:     h.numPages,
+		NumPages:     numPages,
 		TotalStories: len(allStories),
 		Stories:      allStories,
 	}
@@ -66,6 +99,159 @@ func (h *Handler) HandleFetch(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, response)
 }
 
+// HandleItem handles POST /item requests
+func (h *Handler) HandleItem(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req ItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	id, err := parseItemID(req.ID.String())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var response ItemResponse
+	var comments []Comment
+
+	// Fetch each page sequentially, following the "More" link of long
+	// discussions
+	url := itemURL(id)
+	for page := 1; page <= maxItemPages; page++ {
+		resp, err := h.rateLimiter.FetchURL(url)
+		if err != nil {
+			writeError(w, http.StatusBadGateway, fmt.Sprintf("Failed to fetch page %d: %v", page, err))
+			return
+		}
+
+		fetchedAt, err := time.Parse(time.RFC3339, resp.FetchedAt)
+		if err != nil {
+			fetchedAt = time.Now()
+		}
+		parsed, err := ParseHNItemPage(resp.HTML, page, fetchedAt)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to parse page %d: %v", page, err))
+			return
+		}
+
+		// The story and its text are taken from the first page
+		if page == 1 {
+			if parsed.Story == nil {
+				writeError(w, http.StatusNotFound, fmt.Sprintf("Item %s not found or not a story", id))
+				return
+			}
+			response.FetchedAt = resp.FetchedAt
+			response.Story = *parsed.Story
+			response.TextHTML = parsed.TextHTML
+			response.Text = parsed.Text
+		}
+
+		comments = append(comments, parsed.Comments...)
+		response.NumPages = page
+
+		if parsed.More == "" {
+			break
+		}
+		if page == maxItemPages {
+			response.Truncated = true
+			break
+		}
+		url, err = nextPageURL(url, parsed.More)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to parse page %d: %v", page, err))
+			return
+		}
+	}
+
+	response.Comments = buildCommentTree(response.Story.ID, comments)
+	response.TotalComments = len(comments)
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// HandleUser handles POST /user requests
+func (h *Handler) HandleUser(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req UserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	username, err := parseUsername(req.Username)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	resp, err := h.rateLimiter.FetchURL(userURL(username))
+	if err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Sprintf("Failed to fetch user page: %v", err))
+		return
+	}
+	profile, err := ParseHNUserPage(resp.HTML)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to parse user page: %v", err))
+		return
+	}
+	if profile == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("User %s not found", username))
+		return
+	}
+
+	response := UserResponse{
+		UserProfile: *profile,
+		FetchedAt:   resp.FetchedAt,
+	}
+
+	// Submissions are optional, so failing to get them does not fail the
+	// request. HN's robots.txt disallows /submitted, which a Rate Limiter
+	// obeying it refuses.
+	if req.Submissions {
+		stories, err := h.fetchSubmissions(username)
+		var rlErr *RateLimiterError
+		switch {
+		case errors.As(err, &rlErr) && rlErr.Code == codeRobotsDisallowed:
+			response.SubmissionsError = "Submissions are not available: Hacker News' robots.txt disallows submitted pages"
+		case err != nil:
+			response.SubmissionsError = err.Error()
+		default:
+			response.Submissions = stories
+		}
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// fetchSubmissions fetches the first page of a user's submissions
+func (h *Handler) fetchSubmissions(username string) ([]Story, error) {
+	resp, err := h.rateLimiter.FetchURL(submittedURL(username))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch submissions: %w", err)
+	}
+	fetchedAt, err := time.Parse(time.RFC3339, resp.FetchedAt)
+	if err != nil {
+		fetchedAt = time.Now()
+	}
+	stories, _, err := ParseHNPage(resp.HTML, 1, fetchedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse submissions: %w", err)
+	}
+	if stories == nil {
+		stories = []Story{}
+	}
+	return stories, nil
+}
+
 // HandleDoc handles GET /doc requests
 func (h *Handler) HandleDoc(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -76,21 +262,45 @@ func (h *Handler) HandleDoc(w http.ResponseWriter, r *http.Request) {
 	doc := map[string]interface{}{
 		"name":        "Parser API",
 		"version":     "1.0.0",
-		"description": "Fetches and parses Hacker News top stories via a rate-limited fetcher",
+		"description": "Fetches and parses Hacker News story listings, discussions and user profiles via a rate-limited fetcher",
 		"endpoints": []map[string]interface{}{
 			{
 				"method":      "POST",
 				"path":        "/fetch",
-				"description": "Fetches N pages of Hacker News top stories and returns parsed data",
+				"description": "Fetches up to N pages of a Hacker News feed (the front page by default) and returns parsed data. Pages are followed through each page's \"More\" link, so every feed uses its own pagination; fewer pages are returned if the feed ends first",
 				"request": map[string]interface{}{
-					"body":         nil,
-					"content_type": nil,
+					"content_type": "application/json",
+					"body": map[string]interface{}{
+						"feed": map[string]interface{}{
+							"type":        "string",
+							"required":    false,
+							"description": "The feed to fetch, one of " + strings.Join(feedNames(), ", ") + ". Defaults to " + DefaultFeed + ". \"front\" may also be given as \"front?day=YYYY-MM-DD\"",
+						},
+						"day": map[string]interface{}{
+							"type":        "string",
+							"format":      "YYYY-MM-DD",
+							"required":    false,
+							"description": "For the front feed only: the day whose front page to fetch. Defaults to the previous day",
+						},
+					},
+					"example": map[string]interface{}{
+						"feed": "newest",
+					},
+					"notes": "The body may be omitted entirely to fetch the front page",
 				},
 				"response": map[string]interface{}{
 					"success": map[string]interface{}{
 						"status_code":  200,
 						"content_type": "application/json",
 						"body": map[string]interface{}{
+							"feed": map[string]interface{}{
+								"type":        "string",
+								"description": "The feed that was fetched",
+							},
+							"day": map[string]interface{}{
+								"type":        "string",
+								"description": "The day of the front feed, if one was requested",
+							},
 							"fetched_at": map[string]interface{}{
 								"type":        "string",
 								"format":      "RFC3339",
@@ -98,7 +308,7 @@ func (h *Handler) HandleDoc(w http.ResponseWriter, r *http.Request) {
 							},
 							"num_pages": map[string]interface{}{
 								"type":        "integer",
-								"description": "Number of Hacker News pages fetched",
+								"description": "Number of Hacker News pages fetched, at most the configured number",
 							},
 							"total_stories": map[string]interface{}{
 								"type":        "integer",
@@ -110,19 +320,27 @@ func (h *Handler) HandleDoc(w http.ResponseWriter, r *http.Request) {
 								"items": map[string]interface{}{
 									"rank": map[string]interface{}{
 										"type":        "integer",
-										"description": "Story's position on Hacker News (1-indexed)",
+										"description": "Story's position in the feed (1-indexed)",
 									},
 									"id": map[string]interface{}{
 										"type":        "string",
 										"description": "Hacker News story ID",
 									},
+									"type": map[string]interface{}{
+										"type":        "string",
+										"description": "One of story, ask, show, job, poll or launch. Jobs are recognized by having no score or submitter, the others by the Ask HN:, Show HN:, Poll: or Launch HN: headline prefix",
+									},
 									"headline": map[string]interface{}{
 										"type":        "string",
 										"description": "Story title/headline",
 									},
 									"url": map[string]interface{}{
 										"type":        "string",
-										"description": "URL of the linked article",
+										"description": "URL of the linked article. Text posts such as Ask HN link to their own discussion page; such relative links are resolved to absolute URLs",
+									},
+									"domain": map[string]interface{}{
+										"type":        "string",
+										"description": "The site shown next to the headline (span.sitestr), empty for text posts",
 									},
 									"username": map[string]interface{}{
 										"type":        "string",
@@ -148,36 +366,65 @@ func (h *Handler) HandleDoc(w http.ResponseWriter, r *http.Request) {
 										"type":        "string",
 										"description": "Unit of the age (minutes, hours, days)",
 									},
+									"submitted_at": map[string]interface{}{
+										"type":        "string",
+										"format":      "RFC3339",
+										"description": "When the story was submitted, from the exact time in the age's title attribute, or estimated from the age text if the page has none. Omitted if neither could be parsed",
+									},
+									"submitted_at_unix": map[string]interface{}{
+										"type":        "integer",
+										"description": "submitted_at as Unix seconds",
+									},
+									"submitted_at_estimated": map[string]interface{}{
+										"type":        "boolean",
+										"description": "True if submitted_at was estimated from the age text (fetch time minus age), so it is only accurate to the age's unit",
+									},
+									"dead": map[string]interface{}{
+										"type":        "boolean",
+										"description": "True if the entry is marked [dead]",
+									},
+									"flagged": map[string]interface{}{
+										"type":        "boolean",
+										"description": "True if the entry is marked [flagged]",
+									},
 									"page": map[string]interface{}{
 										"type":        "integer",
-										"description": "Which Hacker News page this story appeared on",
+										"description": "Which page of the feed this story appeared on",
 									},
 								},
 							},
 						},
 						"example": map[string]interface{}{
+							"feed":          "news",
 							"fetched_at":    "2025-12-06T10:30:00Z",
 							"num_pages":     2,
 							"total_stories": 60,
 							"stories": []map[string]interface{}{
 								{
-									"rank":           1,
-									"id":             "46173547",
-									"headline":       "Tiny Core Linux: a 23 MB Linux distro with graphical desktop",
-									"url":            "http://www.tinycorelinux.net/",
-									"username":       "LorenDB",
-									"points":         221,
-									"comments":       114,
-									"discussion_url": "https://news.ycombinator.com/item?id=46173547",
-									"age_value":      4,
-									"age_unit":       "hours",
-									"page":           1,
+									"rank":                   1,
+									"id":                     "46173547",
+									"type":                   "story",
+									"headline":               "Tiny Core Linux: a 23 MB Linux distro with graphical desktop",
+									"url":                    "http://www.tinycorelinux.net/",
+									"domain":                 "tinycorelinux.net",
+									"username":               "LorenDB",
+									"points":                 221,
+									"comments":               114,
+									"discussion_url":         "https://news.ycombinator.com/item?id=46173547",
+									"age_value":              4,
+									"age_unit":               "hours",
+									"submitted_at":           "2025-12-06T06:12:41Z",
+									"submitted_at_unix":      1764999161,
+									"submitted_at_estimated": false,
+									"dead":                   false,
+									"flagged":                false,
+									"page":                   1,
 								},
 							},
 						},
 					},
 					"error": map[string]interface{}{
-						"status_codes": []int{405, 500, 502},
+						"status_codes": []int{400, 405, 500, 502},
 						"content_type": "application/json",
 						"body": map[string]interface{}{
 							"error": map[string]interface{}{
@@ -186,6 +433,12 @@ func (h *Handler) HandleDoc(w http.ResponseWriter, r *http.Request) {
 							},
 						},
 						"examples": []map[string]interface{}{
+							{
+								"status_code": 400,
+								"body": map[string]interface{}{
+									"error": "unknown feed \"top\" (expected one of ask, best, front, jobs, newest, news, show)",
+								},
+							},
 							{
 								"status_code": 502,
 								"body": map[string]interface{}{
@@ -202,6 +455,278 @@ func (h *Handler) HandleDoc(w http.ResponseWriter, r *http.Request) {
 					},
 				},
 			},
+			{
+				"method":      "POST",
+				"path":        "/item",
+				"description": fmt.Sprintf("Fetches a story's discussion page (item?id=) and returns the story, its text and the full comment tree. Long discussions continue on further pages (&p=2, ...), which are followed through the \"More\" link, up to %d pages", maxItemPages),
+				"request": map[string]interface{}{
+					"content_type": "application/json",
+					"body": map[string]interface{}{
+						"id": map[string]interface{}{
+							"type":        "string or integer",
+							"required":    true,
+							"description": "Hacker News story ID",
+						},
+					},
+					"example": map[string]interface{}{
+						"id": "46173547",
+					},
+				},
+				"response": map[string]interface{}{
+					"success": map[string]interface{}{
+						"status_code":  200,
+						"content_type": "application/json",
+						"body": map[string]interface{}{
+							"fetched_at": map[string]interface{}{
+								"type":        "string",
+								"format":      "RFC3339",
+								"description": "Timestamp when the first page was fetched",
+							},
+							"num_pages": map[string]interface{}{
+								"type":        "integer",
+								"description": "Number of discussion pages fetched",
+							},
+							"truncated": map[string]interface{}{
+								"type":        "boolean",
+								"description": fmt.Sprintf("True if the discussion has more than %d pages and the rest were not fetched", maxItemPages),
+							},
+							"story": map[string]interface{}{
+								"type":        "object",
+								"description": "The story, with the fields of a /fetch story; rank is 0",
+							},
+							"text_html": map[string]interface{}{
+								"type":        "string",
+								"description": "The story's text as HTML, for text posts such as Ask HN; empty otherwise",
+							},
+							"text": map[string]interface{}{
+								"type":        "string",
+								"description": "text_html as plain text",
+							},
+							"total_comments": map[string]interface{}{
+								"type":        "integer",
+								"description": "Number of comments parsed across all pages, including replies",
+							},
+							"comments": map[string]interface{}{
+								"type":        "array",
+								"description": "Top-level comments in page order, each with its replies nested",
+								"items": map[string]interface{}{
+									"id": map[string]interface{}{
+										"type":        "string",
+										"description": "Hacker News comment ID",
+									},
+									"parent_id": map[string]interface{}{
+										"type":        "string",
+										"description": "ID of the comment replied to, or of the story for top-level comments",
+									},
+									"username": map[string]interface{}{
+										"type":        "string",
+										"description": "Username of the author, empty for deleted comments",
+									},
+									"depth": map[string]interface{}{
+										"type":        "integer",
+										"description": "Indent level, 0 for top-level comments",
+									},
+									"age_value": map[string]interface{}{
+										"type":        "integer",
+										"description": "Numeric value of the comment's age",
+									},
+									"age_unit": map[string]interface{}{
+										"type":        "string",
+										"description": "Unit of the age (minutes, hours, days)",
+									},
+									"submitted_at": map[string]interface{}{
+										"type":        "string",
+										"format":      "RFC3339",
+										"description": "When the comment was posted, as for stories",
+									},
+									"submitted_at_unix": map[string]interface{}{
+										"type":        "integer",
+										"description": "submitted_at as Unix seconds",
+									},
+									"submitted_at_estimated": map[string]interface{}{
+										"type":        "boolean",
+										"description": "True if submitted_at was estimated from the age text",
+									},
+									"text_html": map[string]interface{}{
+										"type":        "string",
+										"description": "The comment as HN renders it, without the reply link",
+									},
+									"text": map[string]interface{}{
+										"type":        "string",
+										"description": "The comment as plain text: paragraphs are separated by blank lines and shortened links are replaced by their full URL",
+									},
+									"dead": map[string]interface{}{
+										"type":        "boolean",
+										"description": "True if the comment is marked [dead]",
+									},
+									"flagged": map[string]interface{}{
+										"type":        "boolean",
+										"description": "True if the comment is marked [flagged]",
+									},
+									"deleted": map[string]interface{}{
+										"type":        "boolean",
+										"description": "True if the comment was deleted; its replies are kept",
+									},
+									"page": map[string]interface{}{
+										"type":        "integer",
+										"description": "Which page of the discussion this comment appeared on",
+									},
+									"replies": map[string]interface{}{
+										"type":        "array",
+										"description": "Replies to this comment, with the same fields",
+									},
+								},
+							},
+						},
+						"example": map[string]interface{}{
+							"fetched_at":     "2025-12-06T10:30:00Z",
+							"num_pages":      1,
+							"truncated":      false,
+							"story":          map[string]interface{}{"id": "46173547", "type": "story", "headline": "Tiny Core Linux: a 23 MB Linux distro with graphical desktop"},
+							"text_html":      "",
+							"text":           "",
+							"total_comments": 114,
+							"comments": []map[string]interface{}{
+								{
+									"id":                     "46174012",
+									"parent_id":              "46173547",
+									"username":               "jdoe",
+									"depth":                  0,
+									"age_value":              3,
+									"age_unit":               "hours",
+									"submitted_at":           "2025-12-06T07:02:10Z",
+									"submitted_at_unix":      1765004530,
+									"submitted_at_estimated": false,
+									"text_html":              "I ran it on a 486 for years.<p>Still boots in seconds.</p>",
+									"text":                   "I ran it on a 486 for years.\n\nStill boots in seconds.",
+									"dead":                   false,
+									"flagged":                false,
+									"deleted":                false,
+									"page":                   1,
+									"replies":                []interface{}{},
+								},
+							},
+						},
+					},
+					"error": map[string]interface{}{
+						"status_codes": []int{400, 404, 405, 500, 502},
+						"content_type": "application/json",
+						"examples": []map[string]interface{}{
+							{
+								"status_code": 400,
+								"body": map[string]interface{}{
+									"error": "id is required",
+								},
+							},
+							{
+								"status_code": 404,
+								"body": map[string]interface{}{
+									"error": "Item 99999999999 not found or not a story",
+								},
+							},
+						},
+					},
+				},
+			},
+			{
+				"method":      "POST",
+				"path":        "/user",
+				"description": "Fetches a user's profile page (user?id=) and returns their karma, creation date and about text, and optionally their recent submissions (submitted?id=)",
+				"request": map[string]interface{}{
+					"content_type": "application/json",
+					"body": map[string]interface{}{
+						"username": map[string]interface{}{
+							"type":        "string",
+							"required":    true,
+							"description": "Hacker News username, 2 to 15 letters, digits, _ or -",
+						},
+						"submissions": map[string]interface{}{
+							"type":        "boolean",
+							"required":    false,
+							"description": "Also fetch the first page of the user's submissions. Defaults to false",
+						},
+					},
+					"example": map[string]interface{}{
+						"username":    "pg",
+						"submissions": true,
+					},
+				},
+				"response": map[string]interface{}{
+					"success": map[string]interface{}{
+						"status_code":  200,
+						"content_type": "application/json",
+						"body": map[string]interface{}{
+							"username": map[string]interface{}{
+								"type":        "string",
+								"description": "The username as HN shows it",
+							},
+							"created": map[string]interface{}{
+								"type":        "string",
+								"format":      "RFC3339",
+								"description": "When the account was created; midnight UTC of the creation day if the page only gives the date. Omitted if it could not be parsed",
+							},
+							"created_unix": map[string]interface{}{
+								"type":        "integer",
+								"description": "created as Unix seconds",
+							},
+							"karma": map[string]interface{}{
+								"type":        "integer",
+								"description": "The user's karma",
+							},
+							"about_html": map[string]interface{}{
+								"type":        "string",
+								"description": "The about text as HN renders it",
+							},
+							"about": map[string]interface{}{
+								"type":        "string",
+								"description": "about_html as plain text",
+							},
+							"fetched_at": map[string]interface{}{
+								"type":        "string",
+								"format":      "RFC3339",
+								"description": "Timestamp when the profile page was fetched",
+							},
+							"submissions": map[string]interface{}{
+								"type":        "array",
+								"description": "The user's most recent submissions, with the fields of a /fetch story; rank is the position in the list. null unless requested, or if they could not be fetched",
+							},
+							"submissions_error": map[string]interface{}{
+								"type":        "string",
+								"description": "Why the requested submissions could not be fetched; omitted otherwise. HN's robots.txt disallows submitted pages, so a Rate Limiter obeying it refuses them and this is the usual outcome",
+							},
+						},
+						"example": map[string]interface{}{
+							"username":          "pg",
+							"created":           "2006-10-09T18:21:32Z",
+							"created_unix":      1160418092,
+							"karma":             157316,
+							"about_html":        "Bug fixer.",
+							"about":             "Bug fixer.",
+							"fetched_at":        "2025-12-06T10:30:00Z",
+							"submissions":       nil,
+							"submissions_error": "Submissions are not available: Hacker News' robots.txt disallows submitted pages",
+						},
+					},
+					"error": map[string]interface{}{
+						"status_codes": []int{400, 404, 405, 500, 502},
+						"content_type": "application/json",
+						"examples": []map[string]interface{}{
+							{
+								"status_code": 400,
+								"body": map[string]interface{}{
+									"error": "username is required",
+								},
+							},
+							{
+								"status_code": 404,
+								"body": map[string]interface{}{
+									"error": "User nosuchuser not found",
+								},
+							},
+						},
+					},
+				},
+			},
 			{
 				"method":      "GET",
 				"path":        "/doc",
@@ -223,14 +748,6 @@ func (h *Handler) HandleDoc(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, doc)
 }
 
-// buildHNURL builds the Hacker News URL for a given page number
-func buildHNURL(page int) string {
-	if page == 1 {
-		return "https://news.ycombinator.com/"
-	}
-	return fmt.Sprintf("https://news.ycombinator.com/?p=%d", page)
-}
-
 // writeJSON writes a JSON response
 func writeJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// maxItemPages bounds how many continuation pages of a discussion are
+// followed, so a runaway "More" link cannot keep a request going forever
+const maxItemPages = 10
+
+// ItemPage is one page of a Hacker News item (discussion) page
+type ItemPage struct {
+	// Story is nil if the page has no story, e.g. for a missing item or a
+	// comment's own page
+	Story    *Story
+	TextHTML string
+	Text     string
+	// Comments are in page order, with their depth but without replies
+	Comments []Comment
+	// More is the href of the page's "More" link, empty on the last page
+	More string
+}
+
+// itemURL returns the URL of an item's first page
+func itemURL(id string) string {
+	return fmt.Sprintf("%sitem?id=%s", hnBaseURL, id)
+}
+
+// parseItemID validates an item ID from a request
+func parseItemID(id string) (string, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return "", fmt.Errorf("id is required")
+	}
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || n < 1 {
+		return "", fmt.Errorf("invalid id %q (expected a positive integer)", id)
+	}
+	return strconv.FormatInt(n, 10), nil
+}
+
+// ParseHNItemPage parses the HTML of a Hacker News item page into the story,
+// its text and the comments on the page. fetchedAt is when the page was
+// fetched; comment times that HN does not give exactly are estimated from it.
+func ParseHNItemPage(htmlContent string, pageNum int, fetchedAt time.Time) (*ItemPage, error) {
+	doc, err := html.Parse(strings.NewReader(htmlContent))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	page := &ItemPage{}
+
+	// The story is in <table class="fatitem">, laid out as on listing pages
+	if fatitem := findByClass(doc, "fatitem"); fatitem != nil {
+		if rows := findStoryRows(fatitem); len(rows) > 0 {
+			page.Story = parseStoryRow(rows[0], pageNum)
+			if page.Story != nil && page.Story.SubmittedAt == "" {
+				estimateSubmittedAt(page.Story, fetchedAt)
+			}
+		}
+
+		// Text posts have their text in <div class="toptext">
+		if toptext := findByClass(fatitem, "toptext"); toptext != nil {
+			page.TextHTML = innerHTML(toptext)
+			page.Text = plainText(toptext)
+		}
+	}
+
+	for _, row := range findCommentRows(doc) {
+		comment := parseCommentRow(row, pageNum)
+		if comment == nil {
+			continue
+		}
+		if comment.SubmittedAt == "" {
+			if t, ok := estimateTime(comment.AgeValue, comment.AgeUnit, fetchedAt); ok {
+				setCommentTime(comment, t, true)
+			}
+		}
+		page.Comments = append(page.Comments, *comment)
+	}
+
+	// Long discussions continue on item?id=...&p=2 through a "More" link
+	if moreLink := findByClass(doc, "morelink"); moreLink != nil {
+		page.More = getAttr(moreLink, "href")
+	}
+
+	return page, nil
+}
+
+// findCommentRows finds all <tr class="athing comtr"> elements
+func findCommentRows(n *html.Node) []*html.Node {
+	var rows []*html.Node
+	var f func(*html.Node)
+	f = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "tr" {
+			if hasClass(n, "athing") && hasClass(n, "comtr") {
+				rows = append(rows, n)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			f(c)
+		}
+	}
+	f(n)
+	return rows
+}
+
+// parseCommentRow parses a comment row
+func parseCommentRow(row *html.Node, pageNum int) *Comment {
+	comment := &Comment{ID: getAttr(row, "id"), Page: pageNum, Replies: []Comment{}}
+	if comment.ID == "" {
+		return nil
+	}
+
+	// The indent is in <td class="ind" indent="N">; older pages only have
+	// a spacer image 40px wide per level
+	if ind := findByClass(row, "ind"); ind != nil {
+		if depth, err := strconv.Atoi(getAttr(ind, "indent")); err == nil {
+			comment.Depth = depth
+		} else if img := findFirstElement(ind, "img"); img != nil {
+			width, _ := strconv.Atoi(getAttr(img, "width"))
+			comment.Depth = width / 40
+		}
+	}
+
+	// <span class="comhead"> has the author, the age and the markers
+	var head string
+	if comhead := findByClass(row, "comhead"); comhead != nil {
+		head = getTextContent(comhead)
+		if userLink := findByClass(comhead, "hnuser"); userLink != nil {
+			comment.Username = getTextContent(userLink)
+		}
+		if ageSpan := findByClass(comhead, "age"); ageSpan != nil {
+			if t, ok := parseAgeTitle(getAttr(ageSpan, "title")); ok {
+				setCommentTime(comment, t, false)
+			}
+			if ageLink := findFirstElement(ageSpan, "a"); ageLink != nil {
+				comment.AgeValue, comment.AgeUnit = parseAge(getTextContent(ageLink))
+			}
+		}
+	}
+
+	// The text is in <div class="commtext">, or <span class="commtext"> on
+	// older pages; deleted comments may have no commtext at all
+	textNode := findByClass(row, "commtext")
+	if textNode == nil {
+		textNode = findByClass(row, "comment")
+	}
+	if textNode != nil {
+		comment.TextHTML = innerHTML(textNode)
+		comment.Text = plainText(textNode)
+	}
+
+	comment.Dead = strings.Contains(head, "[dead]") || comment.Text == "[dead]"
+	comment.Flagged = strings.Contains(head, "[flagged]") || comment.Text == "[flagged]"
+	comment.Deleted = comment.Text == "[deleted]"
+
+	return comment
+}
+
+// setCommentTime sets the comment's time in both formats
+func setCommentTime(comment *Comment, t time.Time, estimated bool) {
+	comment.SubmittedAt = t.UTC().Format(time.RFC3339)
+	comment.SubmittedAtUnix = t.Unix()
+	comment.SubmittedAtEstimated = estimated
+}
+
+// buildCommentTree nests comments given in page order, where a comment's
+// replies follow it with a greater depth. Top-level comments get parentID.
+func buildCommentTree(parentID string, comments []Comment) []Comment {
+	tree := []Comment{}
+	for i := 0; i < len(comments); {
+		comment := comments[i]
+		end := i + 1
+		for end < len(comments) && comments[end].Depth > comment.Depth {
+			end++
+		}
+		comment.ParentID = parentID
+		comment.Replies = buildCommentTree(comment.ID, comments[i+1:end])
+		tree = append(tree, comment)
+		i = end
+	}
+	return tree
+}
+
+// innerHTML renders the children of a node, leaving out the reply link HN
+// puts inside comment text
+func innerHTML(n *html.Node) string {
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && hasClass(c, "reply") {
+			continue
+		}
+		html.Render(&sb, c)
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// plainText converts comment or story text to plain text. Paragraphs are
+// separated by blank lines, and links HN shortened with "..." are replaced
+// by their full URL.
+func plainText(n *html.Node) string {
+	var sb strings.Builder
+	var f func(*html.Node)
+	f = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+			return
+		}
+		if n.Type == html.ElementNode {
+			if hasClass(n, "reply") {
+				return
+			}
+			switch n.Data {
+			case "p", "pre":
+				if sb.Len() > 0 {
+					sb.WriteString("\n\n")
+				}
+			case "br":
+				sb.WriteString("\n")
+			case "a":
+				text := getTextContent(n)
+				href := getAttr(n, "href")
+				if prefix, ok := strings.CutSuffix(text, "..."); ok && strings.HasPrefix(href, prefix) {
+					sb.WriteString(href)
+					return
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			f(c)
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		f(c)
+	}
+	return strings.TrimSpace(sb.String())
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseHNItemPage(t *testing.T) {
+	page, err := ParseHNItemPage(readPage(t, "item.html"), 1, fetchedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if page.Story == nil {
+		t.Fatal("story not parsed")
+	}
+	if page.Story.ID != "46170002" || page.Story.Type != TypeAsk || page.Story.Comments != 6 {
+		t.Errorf("story = %s, type %q, %d comments; want 46170002, %q, 6", page.Story.ID, page.Story.Type, page.Story.Comments, TypeAsk)
+	}
+	if want := "Books, papers, anything long.\n\nLast month's thread: https://news.ycombinator.com/item?id=45000000"; page.Text != want {
+		t.Errorf("text = %q, want %q", page.Text, want)
+	}
+	if page.More != "item?id=46170002&p=2" {
+		t.Errorf("More link = %q", page.More)
+	}
+
+	tests := []struct {
+		id          string
+		depth       int
+		username    string
+		submittedAt string
+		estimated   bool
+		dead        bool
+		flagged     bool
+		deleted     bool
+	}{
+		{"46171001", 0, "alice", "2025-12-06T08:10:00Z", false, false, false, false},
+		{"46171002", 1, "bob", "2025-12-06T09:00:00Z", false, false, false, false},
+		{"46171003", 2, "spammer", "2025-12-06T09:10:00Z", false, true, false, false},
+		{"46171004", 1, "", "2025-12-06T09:05:00Z", false, false, false, true},
+		{"46171005", 2, "carol", "2025-12-06T09:20:00Z", true, false, false, false},
+		{"46171006", 0, "dave", "2025-12-06T09:30:00Z", false, false, true, false},
+	}
+	if len(page.Comments) != len(tests) {
+		t.Fatalf("parsed %d comments, want %d", len(page.Comments), len(tests))
+	}
+	for i, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			c := page.Comments[i]
+			if c.ID != tt.id || c.Depth != tt.depth || c.Username != tt.username {
+				t.Errorf("comment %d = %s, depth %d, by %q; want %s, %d, %q", i, c.ID, c.Depth, c.Username, tt.id, tt.depth, tt.username)
+			}
+			if c.SubmittedAt != tt.submittedAt || c.SubmittedAtEstimated != tt.estimated {
+				t.Errorf("submitted at = %q (estimated %v), want %q (estimated %v)", c.SubmittedAt, c.SubmittedAtEstimated, tt.submittedAt, tt.estimated)
+			}
+			if c.Dead != tt.dead || c.Flagged != tt.flagged || c.Deleted != tt.deleted {
+				t.Errorf("dead, flagged, deleted = %v, %v, %v; want %v, %v, %v", c.Dead, c.Flagged, c.Deleted, tt.dead, tt.flagged, tt.deleted)
+			}
+		})
+	}
+}
+
+func TestCommentText(t *testing.T) {
+	page, err := ParseHNItemPage(readPage(t, "item.html"), 1, fetchedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Comments) < 2 {
+		t.Fatalf("parsed %d comments, want at least 2", len(page.Comments))
+	}
+
+	tests := []struct {
+		name    string
+		comment Comment
+		text    string
+	}{
+		{"paragraphs and shortened link", page.Comments[0], "Rereading The Mythical Man-Month.\n\nNotes are here: https://example.com/notes/the-mythical-man-month"},
+		{"line break", page.Comments[1], "Still holds up.\nEspecially chapter 2."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.comment.Text != tt.text {
+				t.Errorf("text = %q, want %q", tt.comment.Text, tt.text)
+			}
+			if strings.Contains(tt.comment.TextHTML, "reply") {
+				t.Errorf("text_html has the reply link: %q", tt.comment.TextHTML)
+			}
+		})
+	}
+}
+
+func TestBuildCommentTree(t *testing.T) {
+	page, err := ParseHNItemPage(readPage(t, "item.html"), 1, fetchedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := buildCommentTree("46170002", page.Comments)
+
+	// shape lists each comment as id<parent, followed by its replies in
+	// parentheses
+	var shape func([]Comment) string
+	shape = func(comments []Comment) string {
+		var parts []string
+		for _, c := range comments {
+			s := c.ID + "<" + c.ParentID
+			if len(c.Replies) > 0 {
+				s += " (" + shape(c.Replies) + ")"
+			}
+			parts = append(parts, s)
+		}
+		return strings.Join(parts, ", ")
+	}
+	want := "46171001<46170002 (46171002<46171001 (46171003<46171002), 46171004<46171001 (46171005<46171004)), 46171006<46170002"
+	if got := shape(tree); got != want {
+		t.Errorf("tree = %s\nwant   %s", got, want)
+	}
+
+	if empty := buildCommentTree("1", nil); empty == nil || len(empty) != 0 {
+		t.Errorf("buildCommentTree with no comments = %#v, want an empty slice", empty)
+	}
+}
@@ -56,6 +56,8 @@ func main() {
 
 	// Set up routes
 	http.HandleFunc("/fetch", handler.HandleFetch)
+	http.HandleFunc("/item", handler.HandleItem)
+	http.HandleFunc("/user", handler.HandleUser)
 	http.HandleFunc("/doc", handler.HandleDoc)
 
 	// Start server
@@ -2,18 +2,24 @@ package main
 
 import (
 	"fmt"
+	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	"golang.org/x/net/html"
 )
 
-// ParseHNPage parses the HTML of a Hacker News page and extracts stories
-func ParseHNPage(htmlContent string, pageNum int) ([]Story, error) {
+// ParseHNPage parses the HTML of a Hacker News page and extracts stories,
+// along with the href of the page's "More" link (empty on the last page).
+// fetchedAt is when the page was fetched; submission times that HN does not
+// give exactly are estimated from it and the age text.
+func ParseHNPage(htmlContent string, pageNum int, fetchedAt time.Time) ([]Story, string, error) {
 	doc, err := html.Parse(strings.NewReader(htmlContent))
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+		return nil, "", fmt.Errorf("failed to parse HTML: %w", err)
 	}
 
 	var stories []Story
@@ -24,11 +30,20 @@ func ParseHNPage(htmlContent string, pageNum int) ([]Story, error) {
 	for _, row := range storyRows {
 		story := parseStoryRow(row, pageNum)
 		if story != nil {
+			if story.SubmittedAt == "" {
+				estimateSubmittedAt(story, fetchedAt)
+			}
 			stories = append(stories, *story)
 		}
 	}
 
-	return stories, nil
+	// The "More" link is <a class="morelink" href="...">
+	var more string
+	if moreLink := findByClass(doc, "morelink"); moreLink != nil {
+		more = getAttr(moreLink, "href")
+	}
+
+	return stories, more, nil
 }
 
 // findStoryRows finds all <tr class="athing submission"> elements
@@ -65,19 +80,27 @@ func parseStoryRow(row *html.Node, pageNum int) *Story {
 	}
 
 	// Find titleline span for headline and URL
+	var link *html.Node
 	titleline := findByClass(row, "titleline")
 	if titleline != nil {
 		// First <a> inside titleline has the headline and URL
-		link := findFirstElement(titleline, "a")
+		link = findFirstElement(titleline, "a")
 		if link != nil {
 			story.Headline = getTextContent(link)
-			story.URL = getAttr(link, "href")
+			// Text posts link to their own item?id= page
+			story.URL = resolveURL(getAttr(link, "href"))
+		}
+
+		// The site shown next to the headline, absent for text posts
+		siteSpan := findByClass(titleline, "sitestr")
+		if siteSpan != nil {
+			story.Domain = getTextContent(siteSpan)
 		}
 	}
 
 	// Build discussion URL
 	if story.ID != "" {
-		story.DiscussionURL = fmt.Sprintf("https://news.ycombinator.com/item?id=%s", story.ID)
+		story.DiscussionURL = fmt.Sprintf("%sitem?id=%s", hnBaseURL, story.ID)
 	}
 
 	// Find the subtext row (next sibling <tr>)
@@ -86,6 +109,13 @@ func parseStoryRow(row *html.Node, pageNum int) *Story {
 		parseSubtextRow(subtextRow, story)
 	}
 
+	// Dead and flagged entries are marked next to the headline or in the
+	// subtext; the headline itself may contain the same words
+	story.Dead = hasMarker(row, link, "[dead]") || hasMarker(subtextRow, nil, "[dead]")
+	story.Flagged = hasMarker(row, link, "[flagged]") || hasMarker(subtextRow, nil, "[flagged]")
+
+	story.Type = classifyStory(story, subtextRow)
+
 	// Only return if we got at least the ID and headline
 	if story.ID == "" || story.Headline == "" {
 		return nil
@@ -94,6 +124,83 @@ func parseStoryRow(row *html.Node, pageNum int) *Story {
 	return story
 }
 
+// Story types reported in Story.Type
+const (
+	TypeStory  = "story"
+	TypeAsk    = "ask"
+	TypeShow   = "show"
+	TypeJob    = "job"
+	TypePoll   = "poll"
+	TypeLaunch = "launch"
+)
+
+// typePrefixes maps the headline prefixes HN uses by convention to the
+// story type they mark
+var typePrefixes = []struct {
+	prefix string
+	typ    string
+}{
+	{"Ask HN:", TypeAsk},
+	{"Show HN:", TypeShow},
+	{"Launch HN:", TypeLaunch},
+	{"Poll:", TypePoll},
+}
+
+// classifyStory returns the story's type. Job posts are the only entries
+// without a score or submitter; the other types are told apart by the
+// headline prefix.
+func classifyStory(story *Story, subtextRow *html.Node) string {
+	if subtextRow != nil && findByClass(subtextRow, "score") == nil && findByClass(subtextRow, "hnuser") == nil {
+		return TypeJob
+	}
+	for _, p := range typePrefixes {
+		if strings.HasPrefix(story.Headline, p.prefix) {
+			return p.typ
+		}
+	}
+	return TypeStory
+}
+
+// resolveURL resolves a link on a Hacker News page, such as "item?id=1",
+// against the site root. Absolute URLs are returned unchanged.
+func resolveURL(href string) string {
+	if href == "" {
+		return ""
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	base, _ := url.Parse(hnBaseURL)
+	return base.ResolveReference(ref).String()
+}
+
+// hasMarker reports whether a text node of n, outside skip, holds marker
+// as a word of its own, like the " [dead]" HN puts after a headline or the
+// " | [flagged]" in a subtext
+func hasMarker(n, skip *html.Node, marker string) bool {
+	if n == nil || n == skip {
+		return false
+	}
+	if n.Type == html.TextNode {
+		words := strings.FieldsFunc(n.Data, func(r rune) bool {
+			return r == '|' || unicode.IsSpace(r)
+		})
+		for _, word := range words {
+			if word == marker {
+				return true
+			}
+		}
+		return false
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if hasMarker(c, skip, marker) {
+			return true
+		}
+	}
+	return false
+}
+
 // parseSubtextRow extracts points, username, age, and comments from the subtext row
 func parseSubtextRow(row *html.Node, story *Story) {
 	// Find score span
@@ -113,9 +220,12 @@ func parseSubtextRow(row *html.Node, story *Story) {
 		story.Username = getTextContent(userLink)
 	}
 
-	// Find age
+	// Find age, and the exact submission time in the span's title
 	ageSpan := findByClass(row, "age")
 	if ageSpan != nil {
+		if t, ok := parseAgeTitle(getAttr(ageSpan, "title")); ok {
+			setSubmittedAt(story, t, false)
+		}
 		ageLink := findFirstElement(ageSpan, "a")
 		if ageLink != nil {
 			ageText := getTextContent(ageLink)
@@ -163,6 +273,66 @@ func parseAge(ageStr string) (int, string) {
 	return 0, ""
 }
 
+// parseAgeTitle parses the title of <span class="age">, which holds the
+// submission time as an ISO timestamp in UTC, followed by the Unix time in
+// newer pages: "2025-12-06T06:30:00 1764999000"
+func parseAgeTitle(title string) (time.Time, bool) {
+	fields := strings.Fields(title)
+	if len(fields) == 0 {
+		return time.Time{}, false
+	}
+
+	// Prefer the Unix time, which is unambiguous
+	if len(fields) > 1 {
+		if secs, err := strconv.ParseInt(fields[1], 10, 64); err == nil && secs > 0 {
+			return time.Unix(secs, 0).UTC(), true
+		}
+	}
+
+	for _, layout := range []string{"2006-01-02T15:04:05", time.RFC3339} {
+		if t, err := time.Parse(layout, fields[0]); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ageUnits maps the units of the age text to their duration; months and
+// years are approximate
+var ageUnits = map[string]time.Duration{
+	"second": time.Second,
+	"minute": time.Minute,
+	"hour":   time.Hour,
+	"day":    24 * time.Hour,
+	"month":  30 * 24 * time.Hour,
+	"year":   365 * 24 * time.Hour,
+}
+
+// estimateSubmittedAt sets the submission time from the age text, counted
+// back from fetchedAt. It leaves it unset if the age could not be parsed.
+func estimateSubmittedAt(story *Story, fetchedAt time.Time) {
+	if t, ok := estimateTime(story.AgeValue, story.AgeUnit, fetchedAt); ok {
+		setSubmittedAt(story, t, true)
+	}
+}
+
+// estimateTime counts an age such as 4 hours back from fetchedAt
+func estimateTime(value int, unit string, fetchedAt time.Time) (time.Time, bool) {
+	d, ok := ageUnits[strings.TrimSuffix(unit, "s")]
+	if !ok || fetchedAt.IsZero() {
+		return time.Time{}, false
+	}
+	age := time.Duration(value) * d
+	return fetchedAt.Add(-age).Truncate(time.Second), true
+}
+
+// setSubmittedAt sets the story's submission time in both formats
+func setSubmittedAt(story *Story, t time.Time, estimated bool) {
+	story.SubmittedAt = t.UTC().Format(time.RFC3339)
+	story.SubmittedAtUnix = t.Unix()
+	story.SubmittedAtEstimated = estimated
+}
+
 // Helper functions for HTML parsing
 
 func hasClass(n *html.Node, class string) bool {
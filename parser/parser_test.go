@@ -0,0 +1,187 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// fetchedAt is when the saved pages in testdata were fetched
+var fetchedAt = time.Date(2025, 12, 6, 10, 0, 0, 0, time.UTC)
+
+// readPage returns a saved page from testdata
+func readPage(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestParseAgeTitle(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		want  time.Time
+		ok    bool
+	}{
+		{"timestamp and unix time", "2025-12-06T06:30:00 1765002600", time.Date(2025, 12, 6, 6, 30, 0, 0, time.UTC), true},
+		{"unix time wins", "2025-12-06T00:00:00 1765002600", time.Date(2025, 12, 6, 6, 30, 0, 0, time.UTC), true},
+		{"timestamp only", "2025-12-06T06:30:00", time.Date(2025, 12, 6, 6, 30, 0, 0, time.UTC), true},
+		{"rfc3339", "2025-12-06T07:30:00+01:00", time.Date(2025, 12, 6, 6, 30, 0, 0, time.UTC), true},
+		{"invalid unix time", "2025-12-06T06:30:00 soon", time.Date(2025, 12, 6, 6, 30, 0, 0, time.UTC), true},
+		{"empty", "", time.Time{}, false},
+		{"garbage", "yesterday", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseAgeTitle(tt.title)
+			if ok != tt.ok || !got.Equal(tt.want) {
+				t.Errorf("parseAgeTitle(%q) = %v, %v; want %v, %v", tt.title, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		text  string
+		value int
+		unit  string
+	}{
+		{"4 hours ago", 4, "hours"},
+		{"1 minute ago", 1, "minute"},
+		{"30 days ago", 30, "days"},
+		{"on Dec 5, 2025", 0, ""},
+		{"", 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			value, unit := parseAge(tt.text)
+			if value != tt.value || unit != tt.unit {
+				t.Errorf("parseAge(%q) = %d, %q; want %d, %q", tt.text, value, unit, tt.value, tt.unit)
+			}
+		})
+	}
+}
+
+func TestEstimateTime(t *testing.T) {
+	tests := []struct {
+		value int
+		unit  string
+		want  time.Time
+		ok    bool
+	}{
+		{4, "hours", fetchedAt.Add(-4 * time.Hour), true},
+		{1, "minute", fetchedAt.Add(-time.Minute), true},
+		{2, "days", fetchedAt.Add(-48 * time.Hour), true},
+		{1, "month", fetchedAt.Add(-30 * 24 * time.Hour), true},
+		{3, "fortnights", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.unit, func(t *testing.T) {
+			got, ok := estimateTime(tt.value, tt.unit, fetchedAt)
+			if ok != tt.ok || !got.Equal(tt.want) {
+				t.Errorf("estimateTime(%d, %q) = %v, %v; want %v, %v", tt.value, tt.unit, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+	if _, ok := estimateTime(4, "hours", time.Time{}); ok {
+		t.Error("estimated a time without a fetch time")
+	}
+}
+
+func TestParseHNPageSubmittedAt(t *testing.T) {
+	// The age span of older pages has no title, so the time is estimated
+	const oldPage = `<table>
+<tr class="athing submission" id="100"><td class="title"><span class="rank">1.</span></td><td class="title"><span class="titleline"><a href="https://example.com/">Old markup</a></span></td></tr>
+<tr><td class="subtext"><span class="subline"><span class="score">5 points</span> by <a href="user?id=carol" class="hnuser">carol</a> <span class="age"><a href="item?id=100">3 hours ago</a></span></span></td></tr>
+</table>`
+
+	newest, _, err := ParseHNPage(readPage(t, "newest.html"), 1, fetchedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, _, err := ParseHNPage(oldPage, 1, fetchedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		story     Story
+		want      string
+		unix      int64
+		estimated bool
+	}{
+		{"exact", newest[0], "2025-12-06T09:58:00Z", 1765015080, false},
+		{"exact second story", newest[1], "2025-12-06T09:55:00Z", 1765014900, false},
+		{"estimated", old[0], "2025-12-06T07:00:00Z", 1765004400, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.story.SubmittedAt != tt.want || tt.story.SubmittedAtUnix != tt.unix || tt.story.SubmittedAtEstimated != tt.estimated {
+				t.Errorf("submitted at = %q (%d, estimated %v), want %q (%d, estimated %v)",
+					tt.story.SubmittedAt, tt.story.SubmittedAtUnix, tt.story.SubmittedAtEstimated, tt.want, tt.unix, tt.estimated)
+			}
+		})
+	}
+}
+
+func TestParseHNPageStories(t *testing.T) {
+	stories, _, err := ParseHNPage(readPage(t, "news.html"), 1, fetchedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byID := make(map[string]Story)
+	for _, s := range stories {
+		byID[s.ID] = s
+	}
+
+	tests := []struct {
+		name     string
+		id       string
+		typ      string
+		url      string
+		domain   string
+		username string
+		points   int
+		comments int
+		dead     bool
+		flagged  bool
+	}{
+		{"link", "46170001", TypeStory, "https://example.com/articles/compilers", "example.com", "pg", 412, 114, false, false},
+		{"ask text post", "46170002", TypeAsk, "https://news.ycombinator.com/item?id=46170002", "", "dang", 97, 1, false, false},
+		{"show", "46170003", TypeShow, "https://github.com/example/tiny-db", "github.com/example", "alice", 250, 48, false, false},
+		{"job", "46170004", TypeJob, "https://www.ycombinator.com/companies/acme/jobs/1-engineer", "ycombinator.com", "", 0, 0, false, false},
+		{"marker word in headline", "46170005", TypeStory, "https://example.net/why-dead-links", "example.net", "bob", 55, 12, false, false},
+		{"dead", "46170006", TypeStory, "https://spam.example/offer", "spam.example", "spammer", 1, 0, true, false},
+		{"flagged", "46170007", TypeStory, "https://example.org/rant", "example.org", "carol", 8, 3, false, true},
+		{"poll", "46170008", TypePoll, "https://news.ycombinator.com/item?id=46170008", "", "dave", 30, 20, false, false},
+		{"launch", "46170009", TypeLaunch, "https://acme.example/", "acme.example", "eve", 60, 25, false, false},
+	}
+	if len(stories) != len(tests) {
+		t.Fatalf("parsed %d stories, want %d", len(stories), len(tests))
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, ok := byID[tt.id]
+			if !ok {
+				t.Fatalf("story %s not parsed", tt.id)
+			}
+			if s.Type != tt.typ {
+				t.Errorf("type = %q, want %q", s.Type, tt.typ)
+			}
+			if s.URL != tt.url || s.Domain != tt.domain {
+				t.Errorf("url, domain = %q, %q; want %q, %q", s.URL, s.Domain, tt.url, tt.domain)
+			}
+			if s.Username != tt.username || s.Points != tt.points || s.Comments != tt.comments {
+				t.Errorf("by %q, %d points, %d comments; want %q, %d, %d", s.Username, s.Points, s.Comments, tt.username, tt.points, tt.comments)
+			}
+			if s.Dead != tt.dead || s.Flagged != tt.flagged {
+				t.Errorf("dead, flagged = %v, %v; want %v, %v", s.Dead, s.Flagged, tt.dead, tt.flagged)
+			}
+		})
+	}
+}
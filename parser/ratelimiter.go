@@ -22,6 +22,25 @@ func NewRateLimiterClient(port int) *RateLimiterClient {
 	}
 }
 
+// codeRobotsDisallowed is the Rate Limiter's error code for a URL that the
+// host's robots.txt disallows
+const codeRobotsDisallowed = "robots_disallowed"
+
+// RateLimiterError is an error response from the Rate Limiter. Code is its
+// machine-readable error code, if it gave one
+type RateLimiterError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *RateLimiterError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("rate limiter error: %s", e.Message)
+	}
+	return fmt.Sprintf("rate limiter returned status %d", e.StatusCode)
+}
+
 // FetchURL requests the Rate Limiter to fetch the given URL
 func (r *RateLimiterClient) FetchURL(url string) (*RateLimiterResponse, error) {
 	reqBody := RateLimiterRequest{URL: url}
@@ -48,11 +67,12 @@ func (r *RateLimiterClient) FetchURL(url string) (*RateLimiterResponse, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		var errResp ErrorResponse
-		if json.Unmarshal(body, &errResp) == nil && errResp.Error != "" {
-			return nil, fmt.Errorf("rate limiter error: %s", errResp.Error)
+		var errResp struct {
+			Error string `json:"error"`
+			Code  string `json:"code"`
 		}
-		return nil, fmt.Errorf("rate limiter returned status %d", resp.StatusCode)
+		json.Unmarshal(body, &errResp)
+		return nil, &RateLimiterError{StatusCode: resp.StatusCode, Code: errResp.Code, Message: errResp.Error}
 	}
 
 	var result RateLimiterResponse
@@ -1,28 +1,119 @@
 package main
 
-// Story represents a single Hacker News story with all its metadata
+import "encoding/json"
+
+// Story represents a single Hacker News story with all its metadata.
+// Type is one of the Type constants; Domain is the site shown next to the
+// headline, empty for text posts such as Ask HN. SubmittedAt (RFC3339) and
+// SubmittedAtUnix come from the exact time HN gives, or are estimated from
+// the age text, which SubmittedAtEstimated reports; both are omitted if
+// neither could be parsed.
 type Story struct {
-	Rank          int    `json:"rank"`
-	ID            string `json:"id"`
-	Headline      string `json:"headline"`
-	URL           string `json:"url"`
-	Username      string `json:"username"`
-	Points        int    `json:"points"`
-	Comments      int    `json:"comments"`
-	DiscussionURL string `json:"discussion_url"`
-	AgeValue      int    `json:"age_value"`
-	AgeUnit       string `json:"age_unit"`
-	Page          int    `json:"page"`
+	Rank                 int    `json:"rank"`
+	ID                   string `json:"id"`
+	Type                 string `json:"type"`
+	Headline             string `json:"headline"`
+	URL                  string `json:"url"`
+	Domain               string `json:"domain"`
+	Username             string `json:"username"`
+	Points               int    `json:"points"`
+	Comments             int    `json:"comments"`
+	DiscussionURL        string `json:"discussion_url"`
+	AgeValue             int    `json:"age_value"`
+	AgeUnit              string `json:"age_unit"`
+	SubmittedAt          string `json:"submitted_at,omitempty"`
+	SubmittedAtUnix      int64  `json:"submitted_at_unix,omitempty"`
+	SubmittedAtEstimated bool   `json:"submitted_at_estimated"`
+	Dead                 bool   `json:"dead"`
+	Flagged              bool   `json:"flagged"`
+	Page                 int    `json:"page"`
+}
+
+// FetchRequest is the optional request body of POST /fetch
+type FetchRequest struct {
+	Feed string `json:"feed"`
+	Day  string `json:"day"`
 }
 
 // FetchResponse is the response returned by POST /fetch
 type FetchResponse struct {
+	Feed         string  `json:"feed"`
+	Day          string  `json:"day,omitempty"`
 	FetchedAt    string  `json:"fetched_at"`
 	NumPages     int     `json:"num_pages"`
 	TotalStories int     `json:"total_stories"`
 	Stories      []Story `json:"stories"`
 }
 
+// Comment is a comment on a Hacker News item with its replies. Depth is
+// the indent level, 0 for top-level comments. TextHTML is the comment as HN
+// renders it, Text the same as plain text.
+type Comment struct {
+	ID                   string    `json:"id"`
+	ParentID             string    `json:"parent_id"`
+	Username             string    `json:"username"`
+	Depth                int       `json:"depth"`
+	AgeValue             int       `json:"age_value"`
+	AgeUnit              string    `json:"age_unit"`
+	SubmittedAt          string    `json:"submitted_at,omitempty"`
+	SubmittedAtUnix      int64     `json:"submitted_at_unix,omitempty"`
+	SubmittedAtEstimated bool      `json:"submitted_at_estimated"`
+	TextHTML             string    `json:"text_html"`
+	Text                 string    `json:"text"`
+	Dead                 bool      `json:"dead"`
+	Flagged              bool      `json:"flagged"`
+	Deleted              bool      `json:"deleted"`
+	Page                 int       `json:"page"`
+	Replies              []Comment `json:"replies"`
+}
+
+// ItemRequest is the request body of POST /item. The ID may be given as a
+// JSON number or string.
+type ItemRequest struct {
+	ID json.Number `json:"id"`
+}
+
+// ItemResponse is the response returned by POST /item
+type ItemResponse struct {
+	FetchedAt     string    `json:"fetched_at"`
+	NumPages      int       `json:"num_pages"`
+	Truncated     bool      `json:"truncated"`
+	Story         Story     `json:"story"`
+	TextHTML      string    `json:"text_html"`
+	Text          string    `json:"text"`
+	TotalComments int       `json:"total_comments"`
+	Comments      []Comment `json:"comments"`
+}
+
+// UserProfile is a Hacker News user's profile. Created (RFC3339) and
+// CreatedUnix are the exact creation time where the page gives it, midnight
+// UTC of the creation day otherwise. AboutHTML is the about text as HN
+// renders it, About the same as plain text.
+type UserProfile struct {
+	Username    string `json:"username"`
+	Created     string `json:"created,omitempty"`
+	CreatedUnix int64  `json:"created_unix,omitempty"`
+	Karma       int    `json:"karma"`
+	AboutHTML   string `json:"about_html"`
+	About       string `json:"about"`
+}
+
+// UserRequest is the request body of POST /user
+type UserRequest struct {
+	Username    string `json:"username"`
+	Submissions bool   `json:"submissions"`
+}
+
+// UserResponse is the response returned by POST /user. Submissions are only
+// fetched if requested; if that fails, SubmissionsError says why and the
+// profile is returned anyway.
+type UserResponse struct {
+	UserProfile
+	FetchedAt        string  `json:"fetched_at"`
+	Submissions      []Story `json:"submissions"`
+	SubmissionsError string  `json:"submissions_error,omitempty"`
+}
+
 // RateLimiterRequest is the request body sent to the Rate Limiter
 type RateLimiterRequest struct {
 	URL string `json:"url"`
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// usernamePattern matches the usernames HN allows
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{2,15}$`)
+
+// parseUsername validates a username from a request
+func parseUsername(username string) (string, error) {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return "", fmt.Errorf("username is required")
+	}
+	if !usernamePattern.MatchString(username) {
+		return "", fmt.Errorf("invalid username %q (expected 2 to 15 letters, digits, _ or -)", username)
+	}
+	return username, nil
+}
+
+// userURL returns the URL of a user's profile page
+func userURL(username string) string {
+	return fmt.Sprintf("%suser?id=%s", hnBaseURL, url.QueryEscape(username))
+}
+
+// submittedURL returns the URL of the first page of a user's submissions
+func submittedURL(username string) string {
+	return fmt.Sprintf("%ssubmitted?id=%s", hnBaseURL, url.QueryEscape(username))
+}
+
+// ParseHNUserPage parses the HTML of a Hacker News user page. It returns nil
+// if the page has no profile, i.e. the user does not exist.
+func ParseHNUserPage(htmlContent string) (*UserProfile, error) {
+	doc, err := html.Parse(strings.NewReader(htmlContent))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	// The profile is a table of "label:" / value rows, starting with
+	// <tr class="athing" id="username">
+	var profile *UserProfile
+	for _, row := range findAllElements(doc, "tr") {
+		cells := childElements(row, "td")
+		if len(cells) != 2 {
+			continue
+		}
+		label := strings.TrimSuffix(getTextContent(cells[0]), ":")
+		value := cells[1]
+
+		if label == "user" {
+			profile = &UserProfile{Username: getTextContent(value)}
+			// Newer pages give the exact creation time
+			if secs, err := strconv.ParseInt(getAttr(value, "timestamp"), 10, 64); err == nil && secs > 0 {
+				setCreated(profile, time.Unix(secs, 0))
+			}
+			continue
+		}
+		if profile == nil {
+			continue
+		}
+
+		switch label {
+		case "created":
+			if profile.Created == "" {
+				if t, ok := parseCreated(value); ok {
+					setCreated(profile, t)
+				}
+			}
+		case "karma":
+			profile.Karma, _ = strconv.Atoi(strings.ReplaceAll(getTextContent(value), ",", ""))
+		case "about":
+			profile.AboutHTML = innerHTML(value)
+			profile.About = plainText(value)
+		}
+	}
+
+	return profile, nil
+}
+
+// parseCreated parses the creation date of a profile. It links to the
+// front page of that day, front?day=2006-10-09; the text gives the same date
+// as "October 9, 2006".
+func parseCreated(value *html.Node) (time.Time, bool) {
+	if link := findFirstElement(value, "a"); link != nil {
+		if u, err := url.Parse(getAttr(link, "href")); err == nil {
+			if t, err := time.Parse("2006-01-02", u.Query().Get("day")); err == nil {
+				return t, true
+			}
+		}
+	}
+	if t, err := time.Parse("January 2, 2006", getTextContent(value)); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// setCreated sets the profile's creation time in both formats
+func setCreated(profile *UserProfile, t time.Time) {
+	profile.Created = t.UTC().Format(time.RFC3339)
+	profile.CreatedUnix = t.Unix()
+}
+
+// childElements returns the element children of n with the given tag
+func childElements(n *html.Node, tag string) []*html.Node {
+	var children []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == tag {
+			children = append(children, c)
+		}
+	}
+	return children
+}
@@ -0,0 +1,74 @@
+package main
+
+import "testing"
+
+func TestParseHNUserPage(t *testing.T) {
+	tests := []struct {
+		name     string
+		page     string
+		username string
+		created  string
+		unix     int64
+		karma    int
+		about    string
+	}{
+		{"timestamp", "user.html", "pg", "2006-10-09T18:21:32Z", 1160418092, 157316, "Bug fixer.\n\nEssays: http://www.paulgraham.com/articles.html"},
+		{"day link only", "user_old.html", "alice", "2012-03-04T00:00:00Z", 1330819200, 42, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile, err := ParseHNUserPage(readPage(t, tt.page))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if profile == nil {
+				t.Fatal("profile not parsed")
+			}
+			if profile.Username != tt.username || profile.Karma != tt.karma {
+				t.Errorf("user %q with %d karma, want %q with %d", profile.Username, profile.Karma, tt.username, tt.karma)
+			}
+			if profile.Created != tt.created || profile.CreatedUnix != tt.unix {
+				t.Errorf("created = %q (%d), want %q (%d)", profile.Created, profile.CreatedUnix, tt.created, tt.unix)
+			}
+			if profile.About != tt.about {
+				t.Errorf("about = %q, want %q", profile.About, tt.about)
+			}
+		})
+	}
+}
+
+func TestParseHNUserPageMissing(t *testing.T) {
+	// HN answers for an unknown user with a bare line of text
+	profile, err := ParseHNUserPage("No such user.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile != nil {
+		t.Errorf("profile = %+v, want nil", profile)
+	}
+}
+
+func TestParseUsername(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"pg", "pg", false},
+		{" dang ", "dang", false},
+		{"user_name-1", "user_name-1", false},
+		{"", "", true},
+		{"a", "", true},
+		{"sixteen_letters_", "", true},
+		{"bad name", "", true},
+		{"pg&id=1", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseUsername(tt.in)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("parseUsername(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
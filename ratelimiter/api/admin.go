@@ -0,0 +1,217 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"ratelimiter/limiter"
+)
+
+// PolicyResponse represents the response body for GET and PUT /admin/policy.
+type PolicyResponse struct {
+	Mode                   string             `json:"mode"`
+	DefaultIntervalSeconds float64            `json:"default_interval_seconds"`
+	HostIntervalSeconds    map[string]float64 `json:"host_interval_seconds"`
+	Burst                  int                `json:"burst"`
+	ObeyRobots             bool               `json:"obey_robots"`
+	AllowPrivate           bool               `json:"allow_private"`
+	Paused                 bool               `json:"paused"`
+	PausedSince            *time.Time         `json:"paused_since,omitempty"`
+}
+
+// PolicyUpdateRequest represents the request body for PUT /admin/policy.
+// Omitted fields are left unchanged.
+type PolicyUpdateRequest struct {
+	DefaultIntervalSeconds *float64           `json:"default_interval_seconds,omitempty"`
+	HostIntervalSeconds    map[string]float64 `json:"host_interval_seconds,omitempty"`
+	Burst                  *int               `json:"burst,omitempty"`
+}
+
+// PauseResponse represents the response body for POST /admin/pause and
+// POST /admin/resume.
+type PauseResponse struct {
+	Paused      bool       `json:"paused"`
+	PausedSince *time.Time `json:"paused_since,omitempty"`
+}
+
+// HandlePolicy handles GET and PUT /admin/policy requests.
+func (h *Handler) HandlePolicy(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req PolicyUpdateRequest
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			h.sendError(w, fmt.Sprintf("Invalid JSON in request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		update, err := req.toUpdate()
+		if err != nil {
+			h.sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		before := h.rateLimiter.Policy()
+		after, err := h.rateLimiter.UpdatePolicy(update)
+		if err != nil {
+			h.sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if changes := policyChanges(before, after); len(changes) > 0 {
+			log.Printf("Policy changed by %s: %s", r.RemoteAddr, strings.Join(changes, ", "))
+		}
+	default:
+		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.policyResponse())
+}
+
+// HandlePause handles POST /admin/pause requests.
+func (h *Handler) HandlePause(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if h.rateLimiter.Pause() {
+		log.Printf("Outbound requests paused by %s", r.RemoteAddr)
+	}
+	h.sendPauseState(w)
+}
+
+// HandleResume handles POST /admin/resume requests.
+func (h *Handler) HandleResume(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if h.rateLimiter.Resume() {
+		log.Printf("Outbound requests resumed by %s", r.RemoteAddr)
+	}
+	h.sendPauseState(w)
+}
+
+// sendPauseState sends whether the rate limiter is paused.
+func (h *Handler) sendPauseState(w http.ResponseWriter) {
+	var resp PauseResponse
+	resp.Paused, resp.PausedSince = h.pausedSince()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// policyResponse describes the adjustable part of the active policy.
+func (h *Handler) policyResponse() PolicyResponse {
+	policy := h.rateLimiter.Policy()
+
+	resp := PolicyResponse{
+		Mode:                   policy.Mode(),
+		DefaultIntervalSeconds: policy.DefaultInterval.Seconds(),
+		HostIntervalSeconds:    make(map[string]float64, len(policy.HostIntervals)),
+		Burst:                  policy.Burst,
+		ObeyRobots:             policy.ObeyRobots,
+		AllowPrivate:           policy.AllowPrivate,
+	}
+	if resp.Burst < 1 {
+		resp.Burst = 1
+	}
+	for host, interval := range policy.HostIntervals {
+		resp.HostIntervalSeconds[host] = interval.Seconds()
+	}
+	resp.Paused, resp.PausedSince = h.pausedSince()
+	return resp
+}
+
+// pausedSince reports whether the rate limiter is paused and, if so, since when.
+func (h *Handler) pausedSince() (bool, *time.Time) {
+	paused, at := h.rateLimiter.Paused()
+	if !paused {
+		return false, nil
+	}
+	return true, &at
+}
+
+// toUpdate validates the request and converts it to a limiter.PolicyUpdate.
+// Intervals must be at least limiter.MinUpdateInterval: a tiny interval
+// would stop pacing altogether.
+func (req PolicyUpdateRequest) toUpdate() (limiter.PolicyUpdate, error) {
+	var u limiter.PolicyUpdate
+	minSecs := limiter.MinUpdateInterval.Seconds()
+	if req.DefaultIntervalSeconds != nil {
+		if *req.DefaultIntervalSeconds < minSecs {
+			return u, fmt.Errorf("default_interval_seconds must be at least %g", minSecs)
+		}
+		d := seconds(*req.DefaultIntervalSeconds)
+		u.DefaultInterval = &d
+	}
+	if req.HostIntervalSeconds != nil {
+		u.HostIntervals = make(map[string]time.Duration, len(req.HostIntervalSeconds))
+		for host, secs := range req.HostIntervalSeconds {
+			if secs < minSecs {
+				return u, fmt.Errorf("host_interval_seconds for %s must be at least %g", host, minSecs)
+			}
+			u.HostIntervals[host] = seconds(secs)
+		}
+	}
+	if req.Burst != nil && *req.Burst < 1 {
+		return u, fmt.Errorf("burst must be a positive integer")
+	}
+	u.Burst = req.Burst
+	return u, nil
+}
+
+// policyChanges describes the differences between two policies for the log.
+func policyChanges(before, after limiter.Policy) []string {
+	var changes []string
+	if before.DefaultInterval != after.DefaultInterval {
+		changes = append(changes, fmt.Sprintf("default interval %v -> %v", before.DefaultInterval, after.DefaultInterval))
+	}
+
+	hosts := make(map[string]bool)
+	for host := range before.HostIntervals {
+		hosts[host] = true
+	}
+	for host := range after.HostIntervals {
+		hosts[host] = true
+	}
+	sorted := make([]string, 0, len(hosts))
+	for host := range hosts {
+		sorted = append(sorted, host)
+	}
+	sort.Strings(sorted)
+	for _, host := range sorted {
+		old, hadOld := before.HostIntervals[host]
+		cur, hasCur := after.HostIntervals[host]
+		switch {
+		case !hadOld:
+			changes = append(changes, fmt.Sprintf("%s interval %v", host, cur))
+		case !hasCur:
+			changes = append(changes, fmt.Sprintf("%s interval %v -> default", host, old))
+		case old != cur:
+			changes = append(changes, fmt.Sprintf("%s interval %v -> %v", host, old, cur))
+		}
+	}
+
+	if before.Burst != after.Burst {
+		changes = append(changes, fmt.Sprintf("burst %d -> %d", before.Burst, after.Burst))
+	}
+	return changes
+}
+
+// seconds converts fractional seconds to a duration.
+func seconds(secs float64) time.Duration {
+	return time.Duration(secs * float64(time.Second))
+}
@@ -2,33 +2,64 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
+	"ratelimiter/archive"
+	"ratelimiter/jobs"
 	"ratelimiter/limiter"
 )
 
 // Handler holds the dependencies for the API handlers.
 type Handler struct {
 	rateLimiter *limiter.RateLimiter
+	jobs        *jobs.Manager
 }
 
-// NewHandler creates a new Handler with the given rate limiter.
-func NewHandler(rl *limiter.RateLimiter) *Handler {
+// NewHandler creates a new Handler with the given rate limiter and job manager.
+func NewHandler(rl *limiter.RateLimiter, jm *jobs.Manager) *Handler {
 	return &Handler{
 		rateLimiter: rl,
+		jobs:        jm,
 	}
 }
 
 // FetchRequest represents the request body for POST /fetch.
 type FetchRequest struct {
-	URL string `json:"url"`
+	URL      string            `json:"url"`
+	Method   string            `json:"method,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Body     string            `json:"body,omitempty"`
+	Priority string            `json:"priority,omitempty"`
+	MaxAge   int               `json:"max_age,omitempty"`
 }
 
-// ErrorResponse represents an error response.
+// ErrorResponse represents an error response. Code is set for errors that
+// clients may want to tell apart from a plain fetch failure.
 type ErrorResponse struct {
 	Error string `json:"error"`
+	Code  string `json:"code,omitempty"`
 }
 
+// Error codes returned in ErrorResponse.Code.
+const (
+	CodeRobotsDisallowed  = "robots_disallowed"
+	CodeNotInArchive      = "not_in_archive"
+	CodeURLBlocked        = "url_blocked"
+	CodeContentType       = "content_type_not_allowed"
+	CodeRequestNotAllowed = "request_not_allowed"
+	CodeQuotaExceeded     = "quota_exceeded"
+	CodeCircuitOpen       = "circuit_open"
+)
+
+// ClientIDHeader identifies the calling service for fair queuing and quotas.
+const ClientIDHeader = "X-Client-ID"
+
 // HandleFetch handles POST /fetch requests.
 func (h *Handler) HandleFetch(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -47,9 +78,33 @@ func (h *Handler) HandleFetch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := h.rateLimiter.Fetch(req.URL)
+	priority, err := limiter.ParsePriority(req.Priority)
 	if err != nil {
-		h.sendError(w, err.Error(), http.StatusBadGateway)
+		h.sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.MaxAge < 0 {
+		h.sendError(w, "max_age must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.rateLimiter.Fetch(r.Context(), limiter.Request{
+		URL:      req.URL,
+		Client:   r.Header.Get(ClientIDHeader),
+		Method:   req.Method,
+		Header:   requestHeader(req.Headers),
+		Body:     []byte(req.Body),
+		Priority: priority,
+		MaxAge:   time.Duration(req.MaxAge) * time.Second,
+	})
+	if err != nil {
+		if r.Context().Err() != nil {
+			// The client gave up; there is nobody left to answer
+			log.Printf("Client disconnected before %s was fetched", req.URL)
+			return
+		}
+		h.sendFetchError(w, err)
 		return
 	}
 
@@ -58,6 +113,63 @@ func (h *Handler) HandleFetch(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(result)
 }
 
+// HostsResponse represents the response body for GET /hosts.
+type HostsResponse struct {
+	Hosts []limiter.HostStatus `json:"hosts"`
+}
+
+// HandleHosts handles GET /hosts requests.
+func (h *Handler) HandleHosts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(HostsResponse{Hosts: h.rateLimiter.Hosts()})
+}
+
+// QueueResponse represents the response body for GET /queue.
+type QueueResponse struct {
+	Depth int                   `json:"depth"`
+	Hosts []limiter.QueueStatus `json:"hosts"`
+}
+
+// HandleQueue handles GET /queue requests.
+func (h *Handler) HandleQueue(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	resp := QueueResponse{Hosts: h.rateLimiter.Queue()}
+	for _, q := range resp.Hosts {
+		resp.Depth += len(q.Entries)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// RobotsResponse represents the response body for GET /admin/robots.
+type RobotsResponse struct {
+	Robots []limiter.RobotsStatus `json:"robots"`
+}
+
+// HandleRobots handles GET /admin/robots requests.
+func (h *Handler) HandleRobots(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(RobotsResponse{Robots: h.rateLimiter.Robots()})
+}
+
 // HandleDoc handles GET /doc requests.
 func (h *Handler) HandleDoc(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -69,22 +181,55 @@ func (h *Handler) HandleDoc(w http.ResponseWriter, r *http.Request) {
 		"name":        "Rate Limiter API",
 		"version":     "1.0.0",
 		"description": "A rate-limited HTTP fetcher API that retrieves HTML documents from URLs while respecting rate limits to avoid burdening remote websites.",
+		"policy":      h.policyDoc(),
 		"endpoints": []map[string]interface{}{
 			{
 				"method":      "POST",
 				"path":        "/fetch",
-				"description": "Fetches an HTML document from the specified URL. Requests are queued and processed according to the configured rate limit.",
+				"description": "Fetches an HTML document from the specified URL. Requests are queued per host and processed according to that host's rate limit; requests to different hosts do not wait for each other.",
 				"request": map[string]interface{}{
 					"content_type": "application/json",
+					"headers": map[string]interface{}{
+						ClientIDHeader: map[string]string{
+							"required":    "false",
+							"description": "Identifies the calling client for fair queuing and daily quotas. Requests without it count as the client \"anonymous\"; IDs without a configured weight or quota are grouped as \"other\"",
+						},
+					},
 					"body": map[string]interface{}{
 						"url": map[string]string{
 							"type":        "string",
 							"required":    "true",
 							"description": "The URL to fetch",
 						},
+						"method": map[string]string{
+							"type":        "string",
+							"required":    "false",
+							"description": "The HTTP method, GET by default. Must be one of the policy's allowed_methods",
+						},
+						"headers": map[string]string{
+							"type":        "object",
+							"required":    "false",
+							"description": "Extra request headers as a map from name to value, e.g. {\"Accept\": \"application/json\"}. Names must be valid tokens and values may not contain control characters such as CR, LF or NUL. Host, User-Agent, Accept-Encoding, connection, proxy and cache validator headers may not be set",
+						},
+						"body": map[string]string{
+							"type":        "string",
+							"required":    "false",
+							"description": "The request body, e.g. a JSON document for a POST (set Content-Type in headers). Not allowed with GET or HEAD; at most the policy's max_request_body_size bytes",
+						},
+						"priority": map[string]string{
+							"type":        "string",
+							"required":    "false",
+							"description": "One of \"interactive\" (default), \"scheduled\" or \"bulk\". Among one client's requests waiting for the same host, the most urgent is served first; every 30 seconds of waiting promotes a request one level, so low priorities are never starved",
+						},
+						"max_age": map[string]string{
+							"type":        "integer",
+							"required":    "false",
+							"description": "Seconds. If a copy of the URL fetched at most this long ago is cached, it is returned immediately without a network request or waiting for the rate limit",
+						},
 					},
 					"example": map[string]string{
-						"url": "https://example.com",
+						"url":      "https://example.com",
+						"priority": "scheduled",
 					},
 				},
 				"response": map[string]interface{}{
@@ -96,9 +241,37 @@ func (h *Handler) HandleDoc(w http.ResponseWriter, r *http.Request) {
 								"type":        "string",
 								"description": "The URL that was fetched",
 							},
+							"final_url": map[string]string{
+								"type":        "string",
+								"description": "The URL the response came from, after following redirects",
+							},
+							"redirects": map[string]string{
+								"type":        "array",
+								"description": "The redirect chain, one {url, status_code, location} object per hop, in order. Omitted if there were no redirects",
+							},
+							"headers": map[string]string{
+								"type":        "object",
+								"description": "The response headers, as a map from canonical header name to a list of values. Content-Encoding and Content-Length are removed when the body was decompressed",
+							},
 							"html": map[string]string{
 								"type":        "string",
-								"description": "The HTML content retrieved from the URL",
+								"description": "The content retrieved from the URL, converted to UTF-8. Empty for binary content",
+							},
+							"body_base64": map[string]string{
+								"type":        "string",
+								"description": "The body, base64-encoded, if the content type is not text (e.g. images or PDFs) or the text's charset cannot be decoded. Omitted otherwise",
+							},
+							"content_type": map[string]string{
+								"type":        "string",
+								"description": "The media type of the response, from Content-Type or sniffed from the body, without parameters",
+							},
+							"charset": map[string]string{
+								"type":        "string",
+								"description": "The charset the text was declared or detected in before conversion to UTF-8. Omitted for binary content",
+							},
+							"charset_error": map[string]string{
+								"type":        "string",
+								"description": "Why the text could not be converted to UTF-8, e.g. an unsupported charset. The body is then in body_base64 instead of html. Omitted otherwise",
 							},
 							"status_code": map[string]string{
 								"type":        "integer",
@@ -106,30 +279,82 @@ func (h *Handler) HandleDoc(w http.ResponseWriter, r *http.Request) {
 							},
 							"content_length": map[string]string{
 								"type":        "integer",
-								"description": "The size of the response body in bytes",
+								"description": "The size of the response body in bytes, after decompression and before charset conversion",
+							},
+							"truncated": map[string]string{
+								"type":        "boolean",
+								"description": "True if the body exceeded the server's maximum body size and was cut off; content_length is then that maximum",
 							},
 							"fetched_at": map[string]string{
 								"type":        "string",
 								"format":      "RFC3339",
 								"description": "The timestamp when the fetch was performed",
 							},
+							"waited_ms": map[string]string{
+								"type":        "integer",
+								"description": "How long the request waited for a rate limit token, in milliseconds",
+							},
+							"timings": map[string]string{
+								"type":        "object",
+								"description": "Where the time went, in fractional milliseconds: wait_ms (rate limit, queue and robots.txt), dns_ms, connect_ms, tls_ms and ttfb_ms (of the last hop; 0 for steps that did not happen), total_ms (from sending the request until the body was read) and reused_connection",
+							},
+							"from_cache": map[string]string{
+								"type":        "boolean",
+								"description": "True if html came from the cache, either because of max_age or because the server answered 304 Not Modified",
+							},
+							"not_modified": map[string]string{
+								"type":        "boolean",
+								"description": "True if the server answered a conditional request (If-None-Match / If-Modified-Since) with 304; status_code and html are those of the cached 200 response",
+							},
+							"shared": map[string]string{
+								"type":        "boolean",
+								"description": "True if identical GET or HEAD requests (same method, URL, headers and body) were in progress at the same time and this result came from a single upstream fetch shared between them. Other methods are never shared",
+							},
 						},
 						"example": map[string]interface{}{
-							"url":            "https://example.com",
+							"url":       "https://example.com",
+							"final_url": "https://example.com/",
+							"redirects": []map[string]interface{}{
+								{"url": "https://example.com", "status_code": 301, "location": "https://example.com/"},
+							},
+							"headers": map[string][]string{
+								"Content-Type": {"text/html; charset=UTF-8"},
+								"Etag":         {"\"3147526947\""},
+							},
 							"html":           "<!doctype html><html>...</html>",
+							"content_type":   "text/html",
+							"charset":        "utf-8",
 							"status_code":    200,
 							"content_length": 1256,
+							"truncated":      false,
 							"fetched_at":     "2025-12-05T10:30:00Z",
+							"waited_ms":      850,
+							"timings": map[string]interface{}{
+								"wait_ms":           850.412,
+								"dns_ms":            12.104,
+								"connect_ms":        21.53,
+								"tls_ms":            44.871,
+								"ttfb_ms":           131.226,
+								"total_ms":          162.904,
+								"reused_connection": false,
+							},
+							"from_cache":   false,
+							"not_modified": false,
+							"shared":       false,
 						},
 					},
 					"error": map[string]interface{}{
-						"status_codes": []int{400, 405, 502},
+						"status_codes": []int{400, 403, 404, 405, 429, 502, 503},
 						"content_type": "application/json",
 						"body": map[string]interface{}{
 							"error": map[string]string{
 								"type":        "string",
 								"description": "A description of the error",
 							},
+							"code": map[string]string{
+								"type":        "string",
+								"description": "Machine-readable error code, present for errors clients may want to handle specially: \"robots_disallowed\" (403) when the host's robots.txt disallows the URL, \"request_not_allowed\" (400) when the method, headers or body are refused by the fetch policy, \"url_blocked\" (403) when the URL's scheme, host or resolved address is refused by the fetch policy, \"not_in_archive\" (404) when the server runs in replay mode and the URL was not recorded, \"content_type_not_allowed\" (502) when the response's content type is not in the server's allowed list, \"quota_exceeded\" (429) when the client has used its daily quota; Retry-After then gives the seconds until the quota resets, \"circuit_open\" (503) when the host has failed repeatedly and is not contacted until Retry-After seconds have passed. A plain 502 without a code means the fetch itself failed",
+							},
 						},
 						"examples": []map[string]interface{}{
 							{
@@ -144,6 +369,26 @@ func (h *Handler) HandleDoc(w http.ResponseWriter, r *http.Request) {
 									"error": "Invalid JSON in request body",
 								},
 							},
+							{
+								"status_code": 400,
+								"body": map[string]string{
+									"error": "unknown priority \"urgent\" (expected interactive, scheduled or bulk)",
+								},
+							},
+							{
+								"status_code": 403,
+								"body": map[string]string{
+									"error": "disallowed by robots.txt: https://news.ycombinator.com/vote?id=1",
+									"code":  "robots_disallowed",
+								},
+							},
+							{
+								"status_code": 403,
+								"body": map[string]string{
+									"error": "URL blocked: http://localhost:8082/: resolves to 127.0.0.1, a loopback address",
+									"code":  "url_blocked",
+								},
+							},
 							{
 								"status_code": 502,
 								"body": map[string]string{
@@ -154,6 +399,445 @@ func (h *Handler) HandleDoc(w http.ResponseWriter, r *http.Request) {
 					},
 				},
 			},
+			{
+				"method":      "POST",
+				"path":        "/jobs",
+				"description": "Submits an asynchronous fetch and returns immediately with a job ID and an estimated start time. Use this instead of POST /fetch when the rate limit may keep a request waiting for minutes. The fetch is queued exactly like POST /fetch.",
+				"request": map[string]interface{}{
+					"content_type": "application/json",
+					"body": map[string]interface{}{
+						"url": map[string]string{
+							"type":        "string",
+							"required":    "true",
+							"description": "The URL to fetch",
+						},
+						"method": map[string]string{
+							"type":        "string",
+							"required":    "false",
+							"description": "Same as for POST /fetch",
+						},
+						"headers": map[string]string{
+							"type":        "object",
+							"required":    "false",
+							"description": "Same as for POST /fetch",
+						},
+						"body": map[string]string{
+							"type":        "string",
+							"required":    "false",
+							"description": "Same as for POST /fetch",
+						},
+						"priority": map[string]string{
+							"type":        "string",
+							"required":    "false",
+							"description": "Same as for POST /fetch",
+						},
+						"max_age": map[string]string{
+							"type":        "integer",
+							"required":    "false",
+							"description": "Same as for POST /fetch",
+						},
+						"callback_url": map[string]string{
+							"type":        "string",
+							"required":    "false",
+							"description": "An http(s) URL that receives a POST with the finished job (the GET /jobs/{id} body) when the fetch succeeds or fails. It is subject to the same URL rules as fetches (403 url_blocked if refused), so internal addresses need --allow-private",
+						},
+					},
+					"headers": map[string]interface{}{
+						ClientIDHeader: map[string]string{
+							"required":    "false",
+							"description": "Same as for POST /fetch; the job is fetched and counted as this client",
+						},
+					},
+					"example": map[string]string{
+						"url":          "https://news.ycombinator.com/",
+						"priority":     "scheduled",
+						"callback_url": "https://hooks.example.com/superpage",
+					},
+				},
+				"response": map[string]interface{}{
+					"success": map[string]interface{}{
+						"status_code":  202,
+						"content_type": "application/json",
+						"example": map[string]interface{}{
+							"id":              "9f86d081884c7d65",
+							"status":          "pending",
+							"estimated_start": "2025-12-05T10:30:30Z",
+							"status_url":      "/jobs/9f86d081884c7d65",
+						},
+					},
+					"error": map[string]interface{}{
+						"status_codes": []int{400, 403, 405, 429, 503},
+						"description":  "400 with code \"request_not_allowed\" or 403 with code \"url_blocked\" when the fetch policy refuses the request, 429 with code \"quota_exceeded\" and Retry-After when the client has used its daily quota, 503 with code \"circuit_open\" and Retry-After while the host's circuit is open",
+						"content_type": "application/json",
+					},
+				},
+			},
+			{
+				"method":      "GET",
+				"path":        "/jobs/{id}",
+				"description": "Returns the status of a job and, once finished, its result or error. Status is \"pending\", \"succeeded\" or \"failed\". Finished jobs are kept for the server's retention period and then return 404.",
+				"request": map[string]interface{}{
+					"body": nil,
+				},
+				"response": map[string]interface{}{
+					"success": map[string]interface{}{
+						"status_code":  200,
+						"content_type": "application/json",
+						"example": map[string]interface{}{
+							"id":              "9f86d081884c7d65",
+							"url":             "https://news.ycombinator.com/",
+							"priority":        "scheduled",
+							"status":          "succeeded",
+							"callback_url":    "https://hooks.example.com/superpage",
+							"created_at":      "2025-12-05T10:30:00Z",
+							"estimated_start": "2025-12-05T10:30:30Z",
+							"finished_at":     "2025-12-05T10:30:31Z",
+							"result": map[string]interface{}{
+								"url":            "https://news.ycombinator.com/",
+								"html":           "<html>...</html>",
+								"status_code":    200,
+								"content_length": 34567,
+								"fetched_at":     "2025-12-05T10:30:30Z",
+								"waited_ms":      30000,
+							},
+						},
+						"notes": "A failed job has \"error\" and, where applicable, \"code\" (same codes as POST /fetch) instead of \"result\". \"callback_error\" is set if the callback could not be delivered.",
+					},
+					"error": map[string]interface{}{
+						"status_codes": []int{404, 405},
+						"content_type": "application/json",
+					},
+				},
+			},
+			{
+				"method":      "GET",
+				"path":        "/hosts",
+				"description": "Returns the current rate limit state of every host fetched so far. When a host answers 429 or 503 its interval is doubled (up to 64x) and requests are held until its Retry-After time; every 3 consecutive successful responses halve it again.",
+				"request": map[string]interface{}{
+					"body": nil,
+				},
+				"response": map[string]interface{}{
+					"success": map[string]interface{}{
+						"status_code":  200,
+						"content_type": "application/json",
+						"body": map[string]interface{}{
+							"hosts": map[string]interface{}{
+								"type":        "array",
+								"description": "One entry per host, sorted by host name",
+								"items": map[string]interface{}{
+									"host": map[string]string{
+										"type":        "string",
+										"description": "Lower-case host name",
+									},
+									"interval_seconds": map[string]string{
+										"type":        "number",
+										"description": "Configured interval between requests to this host",
+									},
+									"crawl_delay_seconds": map[string]string{
+										"type":        "number",
+										"description": "Crawl-delay from the host's robots.txt, used as a minimum interval (omitted if none)",
+									},
+									"effective_interval_seconds": map[string]string{
+										"type":        "number",
+										"description": "Interval currently enforced: the larger of interval and crawl delay, widened by backoff",
+									},
+									"burst": map[string]string{
+										"type":        "integer",
+										"description": "Current token-bucket capacity (1 while backing off)",
+									},
+									"tokens": map[string]string{
+										"type":        "number",
+										"description": "Tokens currently available",
+									},
+									"backoff_level": map[string]string{
+										"type":        "integer",
+										"description": "Backoff level; the effective interval is interval * 2^backoff_level",
+									},
+									"blocked_until": map[string]string{
+										"type":        "string",
+										"format":      "RFC3339",
+										"description": "Present while the host is blocked by a Retry-After header",
+									},
+									"last_fetch": map[string]string{
+										"type":        "string",
+										"format":      "RFC3339",
+										"description": "Time of the last request sent to this host",
+									},
+								},
+							},
+						},
+						"example": map[string]interface{}{
+							"hosts": []map[string]interface{}{
+								{
+									"host":                       "news.ycombinator.com",
+									"interval_seconds":           1,
+									"effective_interval_seconds": 4,
+									"burst":                      1,
+									"tokens":                     0.25,
+									"backoff_level":              2,
+									"blocked_until":              "2025-12-05T10:31:00Z",
+									"last_fetch":                 "2025-12-05T10:30:00Z",
+								},
+							},
+						},
+					},
+				},
+			},
+			{
+				"method":      "GET",
+				"path":        "/queue",
+				"description": "Returns the requests currently waiting for each host, in the order they are expected to be sent, with estimated start times. The active entry holds the host's turn and is waiting for a token.",
+				"request": map[string]interface{}{
+					"body": nil,
+				},
+				"response": map[string]interface{}{
+					"success": map[string]interface{}{
+						"status_code":  200,
+						"content_type": "application/json",
+						"example": map[string]interface{}{
+							"depth": 2,
+							"hosts": []map[string]interface{}{
+								{
+									"host": "news.ycombinator.com",
+									"entries": []map[string]interface{}{
+										{
+											"url":             "https://news.ycombinator.com/",
+											"client":          "digest",
+											"priority":        "scheduled",
+											"enqueued_at":     "2025-12-05T10:30:00Z",
+											"active":          true,
+											"estimated_start": "2025-12-05T10:30:01Z",
+										},
+										{
+											"url":             "https://news.ycombinator.com/item?id=1",
+											"client":          "archiver",
+											"priority":        "bulk",
+											"enqueued_at":     "2025-12-05T10:29:50Z",
+											"active":          false,
+											"estimated_start": "2025-12-05T10:30:31Z",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			{
+				"method":      "GET",
+				"path":        "/status",
+				"description": "Returns operational counters since startup: requests, cache hits, upstream fetches by host and status class, errors by kind, the current queue depth, and percentiles of the time spent waiting for the rate limit and of upstream latency. Percentiles cover the most recent 1000 fetches.",
+				"request": map[string]interface{}{
+					"body": nil,
+				},
+				"response": map[string]interface{}{
+					"success": map[string]interface{}{
+						"status_code":  200,
+						"content_type": "application/json",
+						"example": map[string]interface{}{
+							"uptime_seconds": 3600,
+							"started_at":     "2025-12-05T09:30:00Z",
+							"paused":         false,
+							"requests_total": 240,
+							"cache_hits":     30,
+							"shared":         4,
+							"fetches_total":  205,
+							"fetches": map[string]map[string]int{
+								"news.ycombinator.com": {"2xx": 200, "3xx": 3, "5xx": 2},
+							},
+							"errors_total": 5,
+							"errors": map[string]int{
+								"robots_disallowed": 1,
+								"fetch_failed":      2,
+								"canceled":          2,
+							},
+							"queue_depth": 3,
+							"wait_seconds": map[string]interface{}{
+								"count": 205, "p50": 0.98, "p90": 2.95, "p99": 29.7,
+							},
+							"upstream_latency_seconds": map[string]interface{}{
+								"count": 205, "p50": 0.21, "p90": 0.48, "p99": 1.3,
+							},
+							"clients": []map[string]interface{}{
+								{
+									"client":         "archiver",
+									"weight":         1,
+									"quota":          500,
+									"used_today":     180,
+									"remaining":      320,
+									"resets_at":      "2025-12-06T00:00:00Z",
+									"requests_total": 180,
+									"rejected_total": 0,
+									"queued":         2,
+								},
+								{
+									"client":         "digest",
+									"weight":         3,
+									"quota":          0,
+									"used_today":     60,
+									"resets_at":      "2025-12-06T00:00:00Z",
+									"requests_total": 60,
+									"rejected_total": 0,
+									"queued":         1,
+								},
+							},
+							"circuits": []map[string]interface{}{
+								{
+									"host":                 "news.ycombinator.com",
+									"state":                "open",
+									"consecutive_failures": 5,
+									"last_error":           "Get \"https://news.ycombinator.com/\": context deadline exceeded (Client.Timeout exceeded while awaiting headers)",
+									"opened_at":            "2025-12-05T10:29:00Z",
+									"retry_at":             "2025-12-05T10:30:00Z",
+								},
+							},
+						},
+						"notes": "Error kinds: robots_disallowed, url_blocked, request_not_allowed, content_type_not_allowed, quota_exceeded, circuit_open, canceled (the client gave up) and fetch_failed (anything else). fetches names hosts with their own interval and the first 100 others; later hosts are counted under \"other\". A shared fetch is counted once in fetches and wait/latency, but once per request in requests_total and shared. clients lists \"anonymous\" and \"other\" once seen and every client configured with a weight or quota; other clients are grouped under \"other\", and a request counts towards the quota only once it returns a result; a quota of 0 means no limit and omits remaining. circuits lists the circuit breaker of every host seen: \"closed\" (normal), \"open\" (failing fast until retry_at) or \"half-open\" (one probe request allowed); an open circuit means the host is down, not that the response could not be parsed.",
+					},
+				},
+			},
+			{
+				"method":      "GET",
+				"path":        "/metrics",
+				"description": "Returns the same counters in the Prometheus text format: ratelimiter_requests_total, ratelimiter_cache_hits_total, ratelimiter_shared_total, ratelimiter_fetches_total{host,class}, ratelimiter_errors_total{kind}, ratelimiter_queue_depth{host}, ratelimiter_effective_interval_seconds{host}, ratelimiter_backoff_level{host}, ratelimiter_circuit_state{host} (0 closed, 1 half-open, 2 open), ratelimiter_client_requests_total{client}, ratelimiter_client_rejected_total{client}, ratelimiter_client_quota_remaining{client}, ratelimiter_paused, ratelimiter_uptime_seconds, and the histograms ratelimiter_wait_seconds and ratelimiter_upstream_latency_seconds.",
+				"request": map[string]interface{}{
+					"body": nil,
+				},
+				"response": map[string]interface{}{
+					"success": map[string]interface{}{
+						"status_code":  200,
+						"content_type": "text/plain; version=0.0.4",
+					},
+				},
+			},
+			{
+				"method":      "GET",
+				"path":        "/admin/robots",
+				"description": "Returns every cached robots.txt with the rules that apply to this rate limiter. Entries are refreshed through the normal rate-limited path once they expire.",
+				"request": map[string]interface{}{
+					"body": nil,
+				},
+				"response": map[string]interface{}{
+					"success": map[string]interface{}{
+						"status_code":  200,
+						"content_type": "application/json",
+						"example": map[string]interface{}{
+							"robots": []map[string]interface{}{
+								{
+									"host":                "news.ycombinator.com",
+									"url":                 "https://news.ycombinator.com/robots.txt",
+									"status_code":         200,
+									"crawl_delay_seconds": 30,
+									"allow":               []string{},
+									"disallow":            []string{"/x?", "/vote?", "/reply?"},
+									"fetched_at":          "2025-12-05T10:30:00Z",
+									"expires_at":          "2025-12-06T10:30:00Z",
+									"expired":             false,
+								},
+							},
+						},
+					},
+				},
+			},
+			{
+				"method":      "GET",
+				"path":        "/admin/policy",
+				"description": "Returns the parts of the policy that can be changed at runtime, and whether outbound requests are paused.",
+				"request": map[string]interface{}{
+					"body": nil,
+				},
+				"response": map[string]interface{}{
+					"success": map[string]interface{}{
+						"status_code":  200,
+						"content_type": "application/json",
+						"example": map[string]interface{}{
+							"mode":                     "fixed-interval",
+							"default_interval_seconds": 1,
+							"host_interval_seconds":    map[string]float64{"news.ycombinator.com": 5},
+							"burst":                    1,
+							"obey_robots":              true,
+							"allow_private":            false,
+							"paused":                   false,
+						},
+					},
+				},
+			},
+			{
+				"method":      "PUT",
+				"path":        "/admin/policy",
+				"description": "Changes the policy without a restart. Every host moves to its new interval and burst at once, keeping the tokens it has earned; requests waiting for a token are rescheduled, so a shorter interval applies immediately. Changes are logged with a timestamp.",
+				"request": map[string]interface{}{
+					"content_type": "application/json",
+					"body": map[string]interface{}{
+						"default_interval_seconds": map[string]string{
+							"type":        "number",
+							"required":    "false",
+							"description": "Interval for hosts without their own; at least 1",
+						},
+						"host_interval_seconds": map[string]string{
+							"type":        "object",
+							"required":    "false",
+							"description": "Map from host name to interval in seconds, each at least 1. Replaces every per-host interval; {} removes them all",
+						},
+						"burst": map[string]string{
+							"type":        "integer",
+							"required":    "false",
+							"description": "Token-bucket capacity of every host; must be positive",
+						},
+					},
+					"notes": "Omitted fields are left unchanged; unknown fields are rejected. The admin endpoints are unauthenticated, so obey_robots and allow_private cannot be changed at runtime, only with --robots and --allow-private at startup, and intervals cannot go below 1 second.",
+					"example": map[string]interface{}{
+						"default_interval_seconds": 2,
+						"host_interval_seconds":    map[string]float64{"news.ycombinator.com": 5},
+					},
+				},
+				"response": map[string]interface{}{
+					"success": map[string]interface{}{
+						"status_code":  200,
+						"content_type": "application/json",
+						"description":  "The updated policy, as returned by GET /admin/policy",
+					},
+					"error": map[string]interface{}{
+						"status_codes": []int{400, 405},
+						"content_type": "application/json",
+					},
+				},
+			},
+			{
+				"method":      "POST",
+				"path":        "/admin/pause",
+				"description": "Stops all outbound requests until POST /admin/resume. Requests already sent complete; cached max_age hits are still served; everything else, including robots.txt fetches and jobs, waits in its host's queue. Pausing is logged with a timestamp.",
+				"request": map[string]interface{}{
+					"body": nil,
+				},
+				"response": map[string]interface{}{
+					"success": map[string]interface{}{
+						"status_code":  200,
+						"content_type": "application/json",
+						"example": map[string]interface{}{
+							"paused":       true,
+							"paused_since": "2025-12-05T10:30:00Z",
+						},
+					},
+				},
+			},
+			{
+				"method":      "POST",
+				"path":        "/admin/resume",
+				"description": "Lets outbound requests be sent again after POST /admin/pause. Resuming is logged with a timestamp.",
+				"request": map[string]interface{}{
+					"body": nil,
+				},
+				"response": map[string]interface{}{
+					"success": map[string]interface{}{
+						"status_code":  200,
+						"content_type": "application/json",
+						"example": map[string]interface{}{
+							"paused": false,
+						},
+					},
+				},
+			},
 			{
 				"method":      "GET",
 				"path":        "/doc",
@@ -179,9 +863,148 @@ func (h *Handler) HandleDoc(w http.ResponseWriter, r *http.Request) {
 	enc.Encode(doc)
 }
 
+// policyDoc describes the active rate limit policy.
+func (h *Handler) policyDoc() map[string]interface{} {
+	policy := h.rateLimiter.Policy()
+
+	hostIntervals := make(map[string]float64, len(policy.HostIntervals))
+	for host, interval := range policy.HostIntervals {
+		hostIntervals[host] = interval.Seconds()
+	}
+
+	burst := policy.Burst
+	if burst < 1 {
+		burst = 1
+	}
+
+	clientWeights := policy.ClientWeights
+	if clientWeights == nil {
+		clientWeights = map[string]float64{}
+	}
+	clientQuotas := policy.ClientQuotas
+	if clientQuotas == nil {
+		clientQuotas = map[string]int{}
+	}
+
+	var description string
+	if policy.Mode() == limiter.ModeTokenBucket {
+		description = fmt.Sprintf("Each host may receive up to %d requests back to back; one more request is allowed every %v (or the host's own interval below) on average.", burst, policy.DefaultInterval)
+	} else {
+		description = fmt.Sprintf("Each host receives at most one request every %v (or the host's own interval below).", policy.DefaultInterval)
+	}
+
+	return map[string]interface{}{
+		"mode":                     policy.Mode(),
+		"description":              description,
+		"default_interval_seconds": policy.DefaultInterval.Seconds(),
+		"host_interval_seconds":    hostIntervals,
+		"burst":                    burst,
+		"obey_robots":              policy.ObeyRobots,
+		"robots_ttl_seconds":       policy.RobotsTTL.Seconds(),
+		"cache_size":               policy.CacheSize,
+		"cache_max_bytes":          policy.CacheMaxBytes,
+		"user_agent":               limiter.UserAgent,
+		"allowed_schemes":          policy.Schemes(),
+		"allowed_hosts":            nonNil(policy.AllowedHosts),
+		"denied_hosts":             nonNil(policy.DeniedHosts),
+		"allow_private":            policy.AllowPrivate,
+		"max_body_size":            policy.MaxBodySize,
+		"allowed_methods":          policy.Methods(),
+		"max_request_body_size":    policy.MaxRequestBodySize,
+		"allowed_content_types":    nonNil(policy.AllowedContentTypes),
+		"client_weights":           clientWeights,
+		"client_quotas":            clientQuotas,
+		"default_quota":            policy.DefaultQuota,
+		"breaker_threshold":        policy.BreakerThreshold,
+		"breaker_cooldown_seconds": policy.BreakerCooldown.Seconds(),
+	}
+}
+
+// requestHeader converts the headers of a FetchRequest or JobRequest.
+func requestHeader(headers map[string]string) http.Header {
+	if len(headers) == 0 {
+		return nil
+	}
+	header := make(http.Header, len(headers))
+	for key, value := range headers {
+		header.Set(key, value)
+	}
+	return header
+}
+
+// nonNil returns list, or an empty list if it is nil, so it encodes as [].
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
+
+// errorStatus maps a fetch error to an HTTP status and error code.
+func errorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, limiter.ErrInvalidURL):
+		return http.StatusBadRequest, ""
+	case errors.Is(err, limiter.ErrDisallowed):
+		return http.StatusForbidden, CodeRobotsDisallowed
+	case errors.Is(err, limiter.ErrRequestNotAllowed):
+		return http.StatusBadRequest, CodeRequestNotAllowed
+	case errors.Is(err, limiter.ErrBlocked):
+		return http.StatusForbidden, CodeURLBlocked
+	case errors.Is(err, limiter.ErrContentType):
+		return http.StatusBadGateway, CodeContentType
+	case errors.Is(err, limiter.ErrQuotaExceeded):
+		return http.StatusTooManyRequests, CodeQuotaExceeded
+	case errors.Is(err, limiter.ErrCircuitOpen):
+		return http.StatusServiceUnavailable, CodeCircuitOpen
+	case errors.Is(err, archive.ErrNotRecorded):
+		return http.StatusNotFound, CodeNotInArchive
+	default:
+		return http.StatusBadGateway, ""
+	}
+}
+
+// ErrorCode returns the error code reported for a fetch error, or "" for a
+// plain fetch failure.
+func ErrorCode(err error) string {
+	_, code := errorStatus(err)
+	return code
+}
+
+// sendFetchError sends the error response for a failed fetch. A client over
+// its quota, or asking for a host whose circuit is open, is told when to
+// come back.
+func (h *Handler) sendFetchError(w http.ResponseWriter, err error) {
+	var quotaErr *limiter.QuotaError
+	var circuitErr *limiter.CircuitError
+	switch {
+	case errors.As(err, &quotaErr):
+		setRetryAfter(w, quotaErr.ResetAt)
+	case errors.As(err, &circuitErr):
+		setRetryAfter(w, circuitErr.RetryAt)
+	}
+	status, code := errorStatus(err)
+	h.sendErrorCode(w, err.Error(), code, status)
+}
+
+// setRetryAfter sets the Retry-After header to the seconds until t, at
+// least 1.
+func setRetryAfter(w http.ResponseWriter, t time.Time) {
+	secs := int(math.Ceil(time.Until(t).Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(secs))
+}
+
 // sendError sends a JSON error response.
 func (h *Handler) sendError(w http.ResponseWriter, message string, statusCode int) {
+	h.sendErrorCode(w, message, "", statusCode)
+}
+
+// sendErrorCode sends a JSON error response with an error code.
+func (h *Handler) sendErrorCode(w http.ResponseWriter, message, code string, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(ErrorResponse{Error: message})
+	json.NewEncoder(w).Encode(ErrorResponse{Error: message, Code: code})
 }
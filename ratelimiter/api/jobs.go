@@ -0,0 +1,129 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"ratelimiter/jobs"
+	"ratelimiter/limiter"
+)
+
+// JobRequest represents the request body for POST /jobs.
+type JobRequest struct {
+	URL         string            `json:"url"`
+	Method      string            `json:"method,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Body        string            `json:"body,omitempty"`
+	Priority    string            `json:"priority,omitempty"`
+	MaxAge      int               `json:"max_age,omitempty"`
+	CallbackURL string            `json:"callback_url,omitempty"`
+}
+
+// JobAccepted represents the response body for POST /jobs.
+type JobAccepted struct {
+	ID             string      `json:"id"`
+	Status         jobs.Status `json:"status"`
+	EstimatedStart time.Time   `json:"estimated_start"`
+	StatusURL      string      `json:"status_url"`
+}
+
+// HandleJobs handles POST /jobs requests.
+func (h *Handler) HandleJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req JobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, "Invalid JSON in request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.URL == "" {
+		h.sendError(w, "URL is required", http.StatusBadRequest)
+		return
+	}
+
+	priority, err := limiter.ParsePriority(req.Priority)
+	if err != nil {
+		h.sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.MaxAge < 0 {
+		h.sendError(w, "max_age must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	if req.CallbackURL != "" {
+		u, err := url.Parse(req.CallbackURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			h.sendError(w, "callback_url must be an absolute http or https URL", http.StatusBadRequest)
+			return
+		}
+		// Callbacks are held to the same URL rules as fetches, so they cannot
+		// reach internal addresses the policy refuses
+		if err := h.rateLimiter.CheckURL(req.CallbackURL); err != nil {
+			h.sendFetchError(w, fmt.Errorf("callback_url: %w", err))
+			return
+		}
+	}
+
+	job, err := h.jobs.Submit(limiter.Request{
+		URL:      req.URL,
+		Client:   r.Header.Get(ClientIDHeader),
+		Method:   req.Method,
+		Header:   requestHeader(req.Headers),
+		Body:     []byte(req.Body),
+		Priority: priority,
+		MaxAge:   time.Duration(req.MaxAge) * time.Second,
+	}, req.CallbackURL)
+	if errors.Is(err, limiter.ErrBlocked) || errors.Is(err, limiter.ErrRequestNotAllowed) || errors.Is(err, limiter.ErrQuotaExceeded) || errors.Is(err, limiter.ErrCircuitOpen) {
+		h.sendFetchError(w, err)
+		return
+	}
+	if err != nil {
+		h.sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(JobAccepted{
+		ID:             job.ID,
+		Status:         job.Status,
+		EstimatedStart: job.EstimatedStart,
+		StatusURL:      "/jobs/" + job.ID,
+	})
+}
+
+// HandleJob handles GET /jobs/{id} requests.
+func (h *Handler) HandleJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/jobs/")
+	if id == "" {
+		h.sendError(w, "job ID is required", http.StatusBadRequest)
+		return
+	}
+
+	job, ok := h.jobs.Get(id)
+	if !ok {
+		h.sendError(w, "job not found (finished jobs expire after the retention period)", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(job)
+}
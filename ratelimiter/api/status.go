@@ -0,0 +1,226 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"ratelimiter/limiter"
+)
+
+// StatusResponse represents the response body for GET /status.
+type StatusResponse struct {
+	UptimeSeconds          int64                       `json:"uptime_seconds"`
+	StartedAt              time.Time                   `json:"started_at"`
+	Paused                 bool                        `json:"paused"`
+	RequestsTotal          int64                       `json:"requests_total"`
+	CacheHits              int64                       `json:"cache_hits"`
+	Shared                 int64                       `json:"shared"`
+	FetchesTotal           int64                       `json:"fetches_total"`
+	Fetches                map[string]map[string]int64 `json:"fetches"`
+	ErrorsTotal            int64                       `json:"errors_total"`
+	Errors                 map[string]int64            `json:"errors"`
+	QueueDepth             int                         `json:"queue_depth"`
+	WaitSeconds            Percentiles                 `json:"wait_seconds"`
+	UpstreamLatencySeconds Percentiles                 `json:"upstream_latency_seconds"`
+	Clients                []limiter.ClientStatus      `json:"clients"`
+	Circuits               []limiter.CircuitStatus     `json:"circuits"`
+}
+
+// Percentiles summarizes a distribution of durations in seconds. The
+// percentiles cover the most recent observations, the count all of them.
+type Percentiles struct {
+	Count int64   `json:"count"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P99   float64 `json:"p99"`
+}
+
+// HandleStatus handles GET /status requests.
+func (h *Handler) HandleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	stats := h.rateLimiter.Stats()
+	paused, _ := h.rateLimiter.Paused()
+
+	resp := StatusResponse{
+		UptimeSeconds:          int64(time.Since(stats.StartedAt).Seconds()),
+		StartedAt:              stats.StartedAt,
+		Paused:                 paused,
+		RequestsTotal:          stats.Requests,
+		CacheHits:              stats.CacheHits,
+		Shared:                 stats.Shared,
+		Fetches:                stats.Fetches,
+		Errors:                 stats.Errors,
+		WaitSeconds:            percentiles(stats.Wait),
+		UpstreamLatencySeconds: percentiles(stats.Latency),
+		Clients:                h.rateLimiter.Clients(),
+		Circuits:               h.rateLimiter.Circuits(),
+	}
+	for _, classes := range stats.Fetches {
+		for _, n := range classes {
+			resp.FetchesTotal += n
+		}
+	}
+	for _, n := range stats.Errors {
+		resp.ErrorsTotal += n
+	}
+	for _, q := range h.rateLimiter.Queue() {
+		resp.QueueDepth += len(q.Entries)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// HandleMetrics handles GET /metrics requests with the Prometheus text
+// exposition format.
+func (h *Handler) HandleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	stats := h.rateLimiter.Stats()
+	paused, _ := h.rateLimiter.Paused()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	writeMetric(w, "ratelimiter_uptime_seconds", "gauge", "Seconds since the rate limiter started.")
+	fmt.Fprintf(w, "ratelimiter_uptime_seconds %s\n", formatFloat(time.Since(stats.StartedAt).Seconds()))
+
+	writeMetric(w, "ratelimiter_paused", "gauge", "1 while outbound requests are paused.")
+	fmt.Fprintf(w, "ratelimiter_paused %d\n", boolValue(paused))
+
+	writeMetric(w, "ratelimiter_requests_total", "counter", "Fetch requests received, including jobs.")
+	fmt.Fprintf(w, "ratelimiter_requests_total %d\n", stats.Requests)
+
+	writeMetric(w, "ratelimiter_cache_hits_total", "counter", "Requests answered from the cache.")
+	fmt.Fprintf(w, "ratelimiter_cache_hits_total %d\n", stats.CacheHits)
+
+	writeMetric(w, "ratelimiter_shared_total", "counter", "Requests answered by a fetch shared with identical requests.")
+	fmt.Fprintf(w, "ratelimiter_shared_total %d\n", stats.Shared)
+
+	writeMetric(w, "ratelimiter_fetches_total", "counter", "Upstream responses by host and status class.")
+	for _, host := range sortedKeys(stats.Fetches) {
+		classes := stats.Fetches[host]
+		for _, class := range sortedKeys(classes) {
+			fmt.Fprintf(w, "ratelimiter_fetches_total{host=%s,class=%s} %d\n", labelValue(host), labelValue(class), classes[class])
+		}
+	}
+
+	writeMetric(w, "ratelimiter_errors_total", "counter", "Failed fetch requests by kind.")
+	for _, kind := range sortedKeys(stats.Errors) {
+		fmt.Fprintf(w, "ratelimiter_errors_total{kind=%s} %d\n", labelValue(kind), stats.Errors[kind])
+	}
+
+	writeMetric(w, "ratelimiter_queue_depth", "gauge", "Requests waiting for or holding a host's turn.")
+	for _, q := range h.rateLimiter.Queue() {
+		fmt.Fprintf(w, "ratelimiter_queue_depth{host=%s} %d\n", labelValue(q.Host), len(q.Entries))
+	}
+
+	hosts := h.rateLimiter.Hosts()
+	writeMetric(w, "ratelimiter_effective_interval_seconds", "gauge", "Interval currently enforced per host, including Crawl-delay and backoff.")
+	for _, s := range hosts {
+		fmt.Fprintf(w, "ratelimiter_effective_interval_seconds{host=%s} %s\n", labelValue(s.Host), formatFloat(s.EffectiveIntervalSeconds))
+	}
+	writeMetric(w, "ratelimiter_backoff_level", "gauge", "Backoff level per host; the interval is multiplied by 2^level.")
+	for _, s := range hosts {
+		fmt.Fprintf(w, "ratelimiter_backoff_level{host=%s} %d\n", labelValue(s.Host), s.BackoffLevel)
+	}
+
+	writeMetric(w, "ratelimiter_circuit_state", "gauge", "Circuit breaker state per host: 0 closed, 1 half-open, 2 open.")
+	for _, c := range h.rateLimiter.Circuits() {
+		fmt.Fprintf(w, "ratelimiter_circuit_state{host=%s} %d\n", labelValue(c.Host), circuitValue(c.State))
+	}
+
+	clients := h.rateLimiter.Clients()
+	writeMetric(w, "ratelimiter_client_requests_total", "counter", "Requests counted against each client's quota.")
+	for _, c := range clients {
+		fmt.Fprintf(w, "ratelimiter_client_requests_total{client=%s} %d\n", labelValue(c.Client), c.Requests)
+	}
+	writeMetric(w, "ratelimiter_client_rejected_total", "counter", "Requests rejected because the client was over its quota.")
+	for _, c := range clients {
+		fmt.Fprintf(w, "ratelimiter_client_rejected_total{client=%s} %d\n", labelValue(c.Client), c.Rejected)
+	}
+	writeMetric(w, "ratelimiter_client_quota_remaining", "gauge", "Requests left today for clients with a quota.")
+	for _, c := range clients {
+		if c.Remaining != nil {
+			fmt.Fprintf(w, "ratelimiter_client_quota_remaining{client=%s} %d\n", labelValue(c.Client), *c.Remaining)
+		}
+	}
+
+	writeHistogram(w, "ratelimiter_wait_seconds", "Time fetches waited for the host's turn, robots.txt and a token.", stats.Wait)
+	writeHistogram(w, "ratelimiter_upstream_latency_seconds", "Time from sending a request upstream to reading its body.", stats.Latency)
+}
+
+// percentiles converts a distribution for StatusResponse.
+func percentiles(d limiter.Distribution) Percentiles {
+	return Percentiles{Count: d.Count, P50: d.P50, P90: d.P90, P99: d.P99}
+}
+
+// writeMetric writes the HELP and TYPE lines of a metric.
+func writeMetric(w io.Writer, name, typ, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// writeHistogram writes a distribution as a Prometheus histogram.
+func writeHistogram(w io.Writer, name, help string, d limiter.Distribution) {
+	writeMetric(w, name, "histogram", help)
+	for _, b := range d.Buckets {
+		fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d\n", name, formatFloat(b.UpperBound), b.Count)
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, d.Count)
+	fmt.Fprintf(w, "%s_sum %s\n", name, formatFloat(d.Sum))
+	fmt.Fprintf(w, "%s_count %d\n", name, d.Count)
+}
+
+// labelValue quotes a label value, escaping backslashes, quotes and newlines.
+func labelValue(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// formatFloat formats a sample value.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// boolValue returns 1 for true and 0 for false.
+func boolValue(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// circuitValue returns the ratelimiter_circuit_state value of a state.
+func circuitValue(state string) int {
+	switch state {
+	case limiter.CircuitHalfOpen:
+		return 1
+	case limiter.CircuitOpen:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// sortedKeys returns the keys of a map, sorted.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
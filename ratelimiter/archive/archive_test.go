@@ -0,0 +1,234 @@
+package archive
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// exchange is one request to archive and the response it got.
+type exchange struct {
+	method string
+	url    string
+	status int
+	body   string
+	at     time.Time
+}
+
+func record(t *testing.T, w *Writer, e exchange) {
+	t.Helper()
+	req, err := http.NewRequest(e.method, e.url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := &http.Response{
+		Status:     fmt.Sprintf("%d %s", e.status, http.StatusText(e.status)),
+		StatusCode: e.status,
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+	}
+	if err := w.Record(req, resp, []byte(e.body), false, e.at); err != nil {
+		t.Fatalf("Record(%s %s): %v", e.method, e.url, err)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	start := time.Date(2025, 12, 6, 10, 0, 0, 0, time.UTC)
+	recorded := []exchange{
+		{"GET", "https://news.ycombinator.com/news", 200, "<html>first</html>", start},
+		{"GET", "https://news.ycombinator.com/news", 200, "<html>second</html>", start.Add(time.Hour)},
+		{"GET", "https://news.ycombinator.com/item?id=1", 404, "", start},
+		{"HEAD", "https://news.ycombinator.com/item?id=1", 200, "", start},
+	}
+
+	tests := []struct {
+		name     string
+		strategy Strategy
+		at       time.Time
+		method   string
+		url      string
+		want     []string // bodies of successive replays
+		status   int
+	}{
+		{"sequential", StrategySequential, time.Time{}, "GET", "https://news.ycombinator.com/news", []string{"<html>first</html>", "<html>second</html>", "<html>second</html>"}, 200},
+		{"nearest", StrategyNearest, start.Add(50 * time.Minute), "GET", "https://news.ycombinator.com/news", []string{"<html>second</html>"}, 200},
+		{"nearest earlier", StrategyNearest, start, "GET", "https://news.ycombinator.com/news", []string{"<html>first</html>"}, 200},
+		{"error status", StrategySequential, time.Time{}, "GET", "https://news.ycombinator.com/item?id=1", []string{""}, 404},
+		{"method matched", StrategySequential, time.Time{}, "HEAD", "https://news.ycombinator.com/item?id=1", []string{""}, 200},
+	}
+
+	dir := t.TempDir()
+	w, err := NewWriter(dir, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range recorded {
+		record(t, w, e)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewReplayer(dir, tt.strategy, tt.at)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Len() != len(recorded) {
+				t.Errorf("Len() = %d, want %d", p.Len(), len(recorded))
+			}
+			for i, want := range tt.want {
+				req, _ := http.NewRequest(tt.method, tt.url, nil)
+				resp, err := p.RoundTrip(req)
+				if err != nil {
+					t.Fatalf("replay %d: %v", i, err)
+				}
+				body, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				if resp.StatusCode != tt.status {
+					t.Errorf("replay %d: status = %d, want %d", i, resp.StatusCode, tt.status)
+				}
+				if string(body) != want {
+					t.Errorf("replay %d: body = %q, want %q", i, body, want)
+				}
+				if got := resp.Header.Get("Content-Type"); got != "text/html; charset=utf-8" {
+					t.Errorf("replay %d: Content-Type = %q", i, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRoundTripNotRecorded(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewWriter(dir, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record(t, w, exchange{"GET", "https://example.com/", 200, "hello", time.Now()})
+	w.Close()
+
+	p, err := NewReplayer(dir, StrategySequential, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("GET", "https://example.com/other", nil)
+	if _, err := p.RoundTrip(req); !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("RoundTrip of an unknown URL = %v, want ErrNotRecorded", err)
+	}
+}
+
+func TestWriterRotatesAndCloses(t *testing.T) {
+	dir := t.TempDir()
+	// Every record is larger than the limit, so each exchange starts a file
+	w, err := NewWriter(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2025, 12, 6, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		record(t, w, exchange{"GET", fmt.Sprintf("https://example.com/%d", i), 200, "body", at})
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.warc.gz"))
+	if len(files) != 3 {
+		t.Errorf("wrote %d WARC files, want 3", len(files))
+	}
+
+	req, _ := http.NewRequest("GET", "https://example.com/late", nil)
+	resp := &http.Response{Status: "200 OK", StatusCode: 200, ProtoMajor: 1, ProtoMinor: 1, Header: http.Header{}}
+	if err := w.Record(req, resp, nil, false, at); err == nil {
+		t.Error("Record after Close succeeded")
+	}
+
+	// All three are replayable from their own files
+	p, err := NewReplayer(dir, StrategySequential, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest("GET", fmt.Sprintf("https://example.com/%d", i), nil)
+		resp, err := p.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("replay %d: %v", i, err)
+		}
+		resp.Body.Close()
+	}
+}
+
+func TestTruncatedRecord(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewWriter(dir, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2025, 12, 6, 10, 0, 0, 0, time.UTC)
+	resp := &http.Response{Status: "200 OK", StatusCode: 200, ProtoMajor: 1, ProtoMinor: 1, Header: http.Header{"Content-Length": {"1000"}}}
+	for _, e := range []struct {
+		url       string
+		truncated bool
+	}{
+		{"https://example.com/whole", false},
+		{"https://example.com/cut", true},
+	} {
+		req, _ := http.NewRequest("GET", e.url, nil)
+		if err := w.Record(req, resp, []byte("first bytes"), e.truncated, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Close()
+
+	index, err := os.ReadFile(filepath.Join(dir, IndexFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(index)), "\n") {
+		var entry IndexEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatal(err)
+		}
+		want := entry.URL == "https://example.com/cut"
+		if entry.Truncated != want {
+			t.Errorf("index entry for %s: truncated = %v, want %v", entry.URL, entry.Truncated, want)
+		}
+
+		// Only the truncated response record is marked
+		f, err := os.Open(filepath.Join(dir, entry.File))
+		if err != nil {
+			t.Fatal(err)
+		}
+		gz, err := gzip.NewReader(io.NewSectionReader(f, entry.Offset, entry.Length))
+		if err != nil {
+			t.Fatal(err)
+		}
+		gz.Multistream(false)
+		var marked bool
+		scanner := bufio.NewScanner(gz)
+		for scanner.Scan() {
+			if scanner.Text() == "WARC-Truncated: length" {
+				marked = true
+			}
+		}
+		f.Close()
+		if marked != want {
+			t.Errorf("record for %s: WARC-Truncated present = %v, want %v", entry.URL, marked, want)
+		}
+	}
+}
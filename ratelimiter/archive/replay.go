@@ -0,0 +1,229 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotRecorded is returned for a request with no matching record.
+var ErrNotRecorded = errors.New("URL not found in archive")
+
+// Strategy selects which of a URL's records answers a replayed request.
+type Strategy string
+
+// Replay strategies.
+const (
+	// StrategySequential serves a URL's records in the order they were
+	// recorded, repeating the last one once all have been served.
+	StrategySequential Strategy = "sequential"
+	// StrategyNearest serves the record closest in time to the replay time.
+	StrategyNearest Strategy = "nearest"
+)
+
+// ParseStrategy parses a strategy name. An empty name means StrategySequential.
+func ParseStrategy(s string) (Strategy, error) {
+	switch st := Strategy(s); st {
+	case "":
+		return StrategySequential, nil
+	case StrategySequential, StrategyNearest:
+		return st, nil
+	default:
+		return "", fmt.Errorf("unknown replay strategy %q (expected %s or %s)", s, StrategySequential, StrategyNearest)
+	}
+}
+
+// Replayer is an http.RoundTripper that answers requests from an archive
+// written by Writer instead of the network.
+type Replayer struct {
+	dir      string
+	strategy Strategy
+	at       time.Time
+
+	entries map[string][]IndexEntry // by method and URL, in recorded order
+	next    map[string]int          // sequential cursor by method and URL
+	mu      sync.Mutex
+}
+
+// NewReplayer loads the index of the archive in dir. at is the time the
+// nearest strategy aims for.
+func NewReplayer(dir string, strategy Strategy, at time.Time) (*Replayer, error) {
+	f, err := os.Open(filepath.Join(dir, IndexFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive index: %w", err)
+	}
+	defer f.Close()
+
+	entries := make(map[string][]IndexEntry)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var e IndexEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("invalid archive index line %d: %w", line, err)
+		}
+		key := recordKey(e.Method, e.URL)
+		entries[key] = append(entries[key], e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read archive index: %w", err)
+	}
+
+	return &Replayer{
+		dir:      dir,
+		strategy: strategy,
+		at:       at,
+		entries:  entries,
+		next:     make(map[string]int),
+	}, nil
+}
+
+// Len returns the number of recorded responses.
+func (p *Replayer) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n := 0
+	for _, entries := range p.entries {
+		n += len(entries)
+	}
+	return n
+}
+
+// RoundTrip returns the recorded response chosen by the strategy, or an
+// error wrapping ErrNotRecorded. A recorded 304 only answers a conditional
+// request.
+func (p *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	entry, ok := p.choose(req)
+	if !ok {
+		return nil, ErrNotRecorded
+	}
+
+	resp, err := p.read(entry, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archived response: %w", err)
+	}
+	return resp, nil
+}
+
+// choose picks the record for the request according to the strategy.
+func (p *Replayer) choose(req *http.Request) (IndexEntry, bool) {
+	key := recordKey(req.Method, req.URL.String())
+	conditional := req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != ""
+	eligible := func(e IndexEntry) bool {
+		return conditional || e.StatusCode != http.StatusNotModified
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entries := p.entries[key]
+	switch p.strategy {
+	case StrategyNearest:
+		best := -1
+		var bestDiff time.Duration
+		for i, e := range entries {
+			if !eligible(e) {
+				continue
+			}
+			diff := e.Date.Sub(p.at)
+			if diff < 0 {
+				diff = -diff
+			}
+			// Later records win ties
+			if best < 0 || diff <= bestDiff {
+				best, bestDiff = i, diff
+			}
+		}
+		if best < 0 {
+			return IndexEntry{}, false
+		}
+		return entries[best], true
+	default:
+		for i := p.next[key]; i < len(entries); i++ {
+			if eligible(entries[i]) {
+				p.next[key] = i + 1
+				return entries[i], true
+			}
+		}
+		// Exhausted: keep serving the last eligible record
+		for i := len(entries) - 1; i >= 0; i-- {
+			if eligible(entries[i]) {
+				return entries[i], true
+			}
+		}
+		return IndexEntry{}, false
+	}
+}
+
+// recordKey identifies the records that can answer a request. Archives
+// written before methods were recorded only hold GETs.
+func recordKey(method, url string) string {
+	if method == "" {
+		method = http.MethodGet
+	}
+	return method + " " + url
+}
+
+// read loads the response record located by the index entry.
+func (p *Replayer) read(entry IndexEntry, req *http.Request) (*http.Response, error) {
+	f, err := os.Open(filepath.Join(p.dir, entry.File))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(io.NewSectionReader(f, entry.Offset, entry.Length))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	// WARC version line and headers, then the HTTP response block
+	r := bufio.NewReader(gz)
+	tp := textproto.NewReader(r)
+	version, err := tp.ReadLine()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(version, "WARC/") {
+		return nil, fmt.Errorf("not a WARC record: %q", version)
+	}
+	header, err := tp.ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	if header.Get("WARC-Type") != "response" {
+		return nil, fmt.Errorf("expected a response record, got %q", header.Get("WARC-Type"))
+	}
+	length, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid record length: %w", err)
+	}
+
+	block := make([]byte, length)
+	if _, err := io.ReadFull(r, block); err != nil {
+		return nil, err
+	}
+
+	return http.ReadResponse(bufio.NewReader(bytes.NewReader(block)), req)
+}
@@ -0,0 +1,292 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// IndexFile is the name of the index kept next to the WARC files.
+const IndexFile = "index.jsonl"
+
+// errWriterClosed is returned by Record after Close.
+var errWriterClosed = errors.New("archive is closed")
+
+// IndexEntry locates one archived response. The index has one JSON object
+// per line, in the order the responses were archived.
+type IndexEntry struct {
+	Method     string    `json:"method,omitempty"`
+	URL        string    `json:"url"`
+	Date       time.Time `json:"date"`
+	StatusCode int       `json:"status_code"`
+	RecordID   string    `json:"record_id"`
+	File       string    `json:"file"`
+	Offset     int64     `json:"offset"`
+	Length     int64     `json:"length"`
+	Truncated  bool      `json:"truncated,omitempty"`
+}
+
+// Writer appends request/response pairs to gzip-compressed WARC files in a
+// directory, starting a new file once the current one reaches maxSize bytes.
+// Each record is its own gzip member, so files can be read record by record.
+type Writer struct {
+	dir     string
+	maxSize int64
+
+	file   *os.File
+	name   string
+	size   int64
+	index  *os.File
+	closed bool
+	mu     sync.Mutex
+}
+
+// NewWriter creates the directory if needed and opens its index for appending.
+func NewWriter(dir string, maxSize int64) (*Writer, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	index, err := os.OpenFile(filepath.Join(dir, IndexFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive index: %w", err)
+	}
+
+	return &Writer{
+		dir:     dir,
+		maxSize: maxSize,
+		index:   index,
+	}, nil
+}
+
+// Record archives a request and its response. body is the response body as
+// read by the client, so any Content-Encoding has already been removed. A
+// body cut off by the client is marked with WARC-Truncated, so it is not
+// mistaken for the whole response.
+func (w *Writer) Record(req *http.Request, resp *http.Response, body []byte, truncated bool, fetchedAt time.Time) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return errWriterClosed
+	}
+	if err := w.rotate(fetchedAt); err != nil {
+		return err
+	}
+
+	responseID := newRecordID()
+	requestID := newRecordID()
+	target := req.URL.String()
+
+	responseOffset := w.size
+	responseHeaders := map[string]string{
+		"WARC-Type":           "response",
+		"WARC-Record-ID":      responseID,
+		"WARC-Date":           fetchedAt.UTC().Format(time.RFC3339),
+		"WARC-Target-URI":     target,
+		"WARC-Payload-Digest": payloadDigest(body),
+		"Content-Type":        "application/http;msgtype=response",
+	}
+	if truncated {
+		// The WARC 1.1 reason for a body cut off at a size limit
+		responseHeaders["WARC-Truncated"] = "length"
+	}
+	responseLength, err := w.writeRecord(responseHeaders, responseBlock(resp, body))
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.writeRecord(map[string]string{
+		"WARC-Type":          "request",
+		"WARC-Record-ID":     requestID,
+		"WARC-Date":          fetchedAt.UTC().Format(time.RFC3339),
+		"WARC-Target-URI":    target,
+		"WARC-Concurrent-To": responseID,
+		"Content-Type":       "application/http;msgtype=request",
+	}, requestBlock(req)); err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(IndexEntry{
+		Method:     req.Method,
+		URL:        target,
+		Date:       fetchedAt.UTC(),
+		StatusCode: resp.StatusCode,
+		RecordID:   responseID,
+		File:       w.name,
+		Offset:     responseOffset,
+		Length:     responseLength,
+		Truncated:  truncated,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal index entry: %w", err)
+	}
+	if _, err := w.index.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write archive index: %w", err)
+	}
+	return nil
+}
+
+// Close syncs and closes the current WARC file and the index. Records
+// arriving after Close fail with an error.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	var errs []error
+	for _, f := range []*os.File{w.file, w.index} {
+		if f == nil {
+			continue
+		}
+		if err := f.Sync(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to sync %s: %w", filepath.Base(f.Name()), err))
+		}
+		if err := f.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close %s: %w", filepath.Base(f.Name()), err))
+		}
+	}
+	w.file = nil
+	return errors.Join(errs...)
+}
+
+// rotate opens a new WARC file, starting with a warcinfo record, if there is
+// no current file or it has reached the size limit. The caller must hold w.mu.
+func (w *Writer) rotate(now time.Time) error {
+	if w.file != nil && w.size < w.maxSize {
+		return nil
+	}
+	if w.file != nil {
+		w.file.Close()
+		w.file = nil
+	}
+
+	// Find a name that is not taken yet, in case of several files per second
+	stamp := now.UTC().Format("20060102150405")
+	for seq := 0; ; seq++ {
+		name := fmt.Sprintf("superpage-%s-%05d.warc.gz", stamp, seq)
+		f, err := os.OpenFile(filepath.Join(w.dir, name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create WARC file: %w", err)
+		}
+		w.file, w.name, w.size = f, name, 0
+		break
+	}
+
+	info := "software: superpage-ratelimiter\r\nformat: WARC File Format 1.1\r\n"
+	_, err := w.writeRecord(map[string]string{
+		"WARC-Type":      "warcinfo",
+		"WARC-Record-ID": newRecordID(),
+		"WARC-Date":      now.UTC().Format(time.RFC3339),
+		"WARC-Filename":  w.name,
+		"Content-Type":   "application/warc-fields",
+	}, []byte(info))
+	return err
+}
+
+// writeRecord writes one WARC record as its own gzip member and returns its
+// compressed length. The caller must hold w.mu.
+func (w *Writer) writeRecord(headers map[string]string, block []byte) (int64, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	bw := bufio.NewWriter(gz)
+
+	bw.WriteString("WARC/1.1\r\n")
+	for _, key := range []string{"WARC-Type", "WARC-Record-ID", "WARC-Date", "WARC-Filename", "WARC-Target-URI", "WARC-Concurrent-To", "WARC-Payload-Digest", "WARC-Truncated", "Content-Type"} {
+		if value, ok := headers[key]; ok {
+			fmt.Fprintf(bw, "%s: %s\r\n", key, value)
+		}
+	}
+	fmt.Fprintf(bw, "Content-Length: %d\r\n\r\n", len(block))
+	bw.Write(block)
+	bw.WriteString("\r\n\r\n")
+
+	if err := bw.Flush(); err != nil {
+		return 0, fmt.Errorf("failed to compress WARC record: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return 0, fmt.Errorf("failed to compress WARC record: %w", err)
+	}
+
+	n, err := w.file.Write(buf.Bytes())
+	w.size += int64(n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to write WARC record: %w", err)
+	}
+	return int64(n), nil
+}
+
+// requestBlock renders the request line, headers and body as sent.
+func requestBlock(req *http.Request) []byte {
+	var body []byte
+	if req.GetBody != nil {
+		if rc, err := req.GetBody(); err == nil {
+			body, _ = io.ReadAll(rc)
+			rc.Close()
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s %s HTTP/1.1\r\n", req.Method, req.URL.RequestURI())
+	fmt.Fprintf(&buf, "Host: %s\r\n", req.URL.Host)
+	req.Header.Write(&buf)
+	if len(body) > 0 {
+		fmt.Fprintf(&buf, "Content-Length: %d\r\n", len(body))
+	}
+	buf.WriteString("\r\n")
+	buf.Write(body)
+	return buf.Bytes()
+}
+
+// responseBlock renders the status line, headers and body. Content-Length
+// is rewritten to match the stored (decoded, possibly truncated) body.
+func responseBlock(resp *http.Response, body []byte) []byte {
+	header := resp.Header.Clone()
+	header.Del("Transfer-Encoding")
+	if resp.Uncompressed {
+		header.Del("Content-Encoding")
+	}
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "HTTP/%d.%d %s\r\n", resp.ProtoMajor, resp.ProtoMinor, resp.Status)
+	header.Write(&buf)
+	buf.WriteString("\r\n")
+	buf.Write(body)
+	return buf.Bytes()
+}
+
+// payloadDigest returns the WARC SHA-1 digest of the body.
+func payloadDigest(body []byte) string {
+	sum := sha1.Sum(body)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
+
+// newRecordID returns a random UUID URN for WARC-Record-ID.
+func newRecordID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,243 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"ratelimiter/limiter"
+)
+
+// Status is the state of a fetch job.
+type Status string
+
+// Job states. A job is pending until its fetch returns.
+const (
+	StatusPending   Status = "pending"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+// Job is an asynchronous fetch submitted through POST /jobs.
+type Job struct {
+	ID             string               `json:"id"`
+	URL            string               `json:"url"`
+	Client         string               `json:"client,omitempty"`
+	Priority       limiter.Priority     `json:"priority"`
+	Status         Status               `json:"status"`
+	CallbackURL    string               `json:"callback_url,omitempty"`
+	CreatedAt      time.Time            `json:"created_at"`
+	EstimatedStart time.Time            `json:"estimated_start"`
+	FinishedAt     *time.Time           `json:"finished_at,omitempty"`
+	Result         *limiter.FetchResult `json:"result,omitempty"`
+	Error          string               `json:"error,omitempty"`
+	Code           string               `json:"code,omitempty"`
+	CallbackError  string               `json:"callback_error,omitempty"`
+}
+
+// Manager runs fetch jobs in the background and keeps finished jobs in
+// memory for the retention period.
+type Manager struct {
+	rateLimiter *limiter.RateLimiter
+	retention   time.Duration
+	errorCode   func(error) string
+	httpClient  *http.Client
+
+	jobs map[string]*Job
+	mu   sync.Mutex
+
+	// running counts the jobs being executed, for Stop to wait for
+	running sync.WaitGroup
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	stopCh    chan struct{}
+	stoppedCh chan struct{}
+}
+
+// NewManager creates a Manager that fetches through rl. errorCode maps a
+// fetch error to the code reported in Job.Code.
+func NewManager(rl *limiter.RateLimiter, retention time.Duration, errorCode func(error) string) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{
+		rateLimiter: rl,
+		retention:   retention,
+		errorCode:   errorCode,
+		httpClient:  rl.NewClient(10 * time.Second),
+		jobs:        make(map[string]*Job),
+		ctx:         ctx,
+		cancel:      cancel,
+		stopCh:      make(chan struct{}),
+		stoppedCh:   make(chan struct{}),
+	}
+}
+
+// Start starts removing expired jobs in the background.
+func (m *Manager) Start() {
+	go m.run()
+}
+
+// Stop cancels pending jobs, waits for every job to return and stops the
+// background cleanup. No job may be submitted after Stop.
+func (m *Manager) Stop() {
+	m.cancel()
+	close(m.stopCh)
+	<-m.stoppedCh
+	m.running.Wait()
+}
+
+// Submit queues a fetch and returns the new job immediately.
+func (m *Manager) Submit(req limiter.Request, callbackURL string) (Job, error) {
+	estimate, err := m.rateLimiter.EstimateStart(req)
+	if err != nil {
+		return Job{}, err
+	}
+
+	id, err := newID()
+	if err != nil {
+		return Job{}, err
+	}
+
+	job := &Job{
+		ID:             id,
+		URL:            req.URL,
+		Client:         req.Client,
+		Priority:       req.Priority,
+		Status:         StatusPending,
+		CallbackURL:    callbackURL,
+		CreatedAt:      time.Now(),
+		EstimatedStart: estimate,
+	}
+
+	m.mu.Lock()
+	m.jobs[id] = job
+	snapshot := *job
+	m.mu.Unlock()
+
+	m.running.Add(1)
+	go func() {
+		defer m.running.Done()
+		m.execute(job, req)
+	}()
+	return snapshot, nil
+}
+
+// Get returns a copy of the job with the given ID.
+func (m *Manager) Get(id string) (Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// execute performs the job's fetch, records the outcome and notifies the
+// callback URL if one was given.
+func (m *Manager) execute(job *Job, req limiter.Request) {
+	result, err := m.rateLimiter.Fetch(m.ctx, req)
+	if m.ctx.Err() != nil {
+		return
+	}
+
+	now := time.Now()
+	m.mu.Lock()
+	job.FinishedAt = &now
+	if err != nil {
+		job.Status = StatusFailed
+		job.Error = err.Error()
+		job.Code = m.errorCode(err)
+	} else {
+		job.Status = StatusSucceeded
+		job.Result = result
+	}
+	snapshot := *job
+	m.mu.Unlock()
+
+	if job.CallbackURL == "" {
+		return
+	}
+	if err := m.notify(snapshot); err != nil {
+		log.Printf("Callback for job %s failed: %v", job.ID, err)
+		m.mu.Lock()
+		job.CallbackError = err.Error()
+		m.mu.Unlock()
+	}
+}
+
+// notify POSTs the finished job to its callback URL. The URL is checked
+// again, since the policy may have changed since the job was submitted.
+func (m *Manager) notify(job Job) error {
+	if err := m.rateLimiter.CheckURL(job.CallbackURL); err != nil {
+		return fmt.Errorf("callback URL refused: %w", err)
+	}
+
+	body, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(m.ctx, http.MethodPost, job.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid callback URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach callback URL: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("callback URL returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// run removes finished jobs older than the retention period until Stop is called.
+func (m *Manager) run() {
+	defer close(m.stoppedCh)
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.expire(time.Now())
+		case <-m.stopCh:
+			return
+		}
+	}
+}
+
+// expire removes jobs that finished before now minus the retention period.
+func (m *Manager) expire(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, job := range m.jobs {
+		if job.FinishedAt != nil && now.Sub(*job.FinishedAt) > m.retention {
+			delete(m.jobs, id)
+		}
+	}
+}
+
+// newID returns a random 16-character hex job ID.
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate job ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,155 @@
+package limiter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MinUpdateInterval is the shortest interval UpdatePolicy accepts. The admin
+// API is unauthenticated, so it may slow pacing down but not switch it off;
+// faster rates can only be set at startup.
+const MinUpdateInterval = time.Second
+
+// PolicyUpdate changes the parts of the policy that can be adjusted while
+// the rate limiter is running. Nil fields are left unchanged. The URL rules,
+// AllowPrivate included, and ObeyRobots are deliberately not among them: the
+// admin API is unauthenticated, so they can only be set at startup.
+type PolicyUpdate struct {
+	DefaultInterval *time.Duration
+	// HostIntervals, if not nil, replaces every per-host interval. An empty
+	// map removes them all.
+	HostIntervals map[string]time.Duration
+	Burst         *int
+}
+
+// UpdatePolicy applies u and returns the resulting policy. Every host's
+// bucket moves to its new interval and burst at once: tokens earned so far
+// are kept, up to the new burst, and requests waiting for a token are
+// rescheduled, so a shorter interval takes effect without waiting out the
+// old one. Intervals must be at least MinUpdateInterval. Nothing is changed
+// if u is invalid.
+func (r *RateLimiter) UpdatePolicy(u PolicyUpdate) (Policy, error) {
+	if u.DefaultInterval != nil && *u.DefaultInterval < MinUpdateInterval {
+		return Policy{}, fmt.Errorf("default interval must be at least %v", MinUpdateInterval)
+	}
+	for host, interval := range u.HostIntervals {
+		if host == "" {
+			return Policy{}, errors.New("empty host name in host intervals")
+		}
+		if interval < MinUpdateInterval {
+			return Policy{}, fmt.Errorf("interval for %s must be at least %v", host, MinUpdateInterval)
+		}
+	}
+	if u.Burst != nil && *u.Burst < 1 {
+		return Policy{}, errors.New("burst must be at least 1")
+	}
+
+	// Holding mu keeps new buckets from being created with the old rates
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.policyMu.Lock()
+	if u.DefaultInterval != nil {
+		r.policy.DefaultInterval = *u.DefaultInterval
+	}
+	if u.HostIntervals != nil {
+		hostIntervals := make(map[string]time.Duration, len(u.HostIntervals))
+		for host, interval := range u.HostIntervals {
+			hostIntervals[strings.ToLower(host)] = interval
+		}
+		r.policy.HostIntervals = hostIntervals
+	}
+	if u.Burst != nil {
+		r.policy.Burst = *u.Burst
+	}
+	burst := r.policy.Burst
+	r.policyMu.Unlock()
+
+	for host, b := range r.buckets {
+		b.setRate(r.IntervalFor(host), burst)
+	}
+	return r.Policy(), nil
+}
+
+// Pause stops every request from being sent until Resume is called.
+// Requests already sent complete normally, and cached responses are still
+// served for max_age hits; everything else waits in its host's queue. It
+// reports whether the rate limiter was running.
+func (r *RateLimiter) Pause() bool {
+	return r.setPaused(true)
+}
+
+// Resume lets requests be sent again after Pause. It reports whether the
+// rate limiter was paused.
+func (r *RateLimiter) Resume() bool {
+	return r.setPaused(false)
+}
+
+// Paused reports whether the rate limiter is paused, and since when.
+func (r *RateLimiter) Paused() (bool, time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.paused, r.pausedAt
+}
+
+// setPaused pauses or resumes every bucket and reports whether that changed
+// anything.
+func (r *RateLimiter) setPaused(paused bool) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.paused == paused {
+		return false
+	}
+	r.paused = paused
+	if paused {
+		r.pausedAt = time.Now()
+	} else {
+		r.pausedAt = time.Time{}
+	}
+	for _, b := range r.buckets {
+		b.setPaused(paused)
+	}
+	return true
+}
+
+// setRate changes the bucket's interval and burst. Tokens earned at the old
+// rate are credited first.
+func (b *bucket) setRate(interval time.Duration, burst int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if burst < 1 {
+		burst = 1
+	}
+	b.refill(time.Now())
+	b.interval = interval
+	b.burst = burst
+	if b.tokens > float64(burst) {
+		b.tokens = float64(burst)
+	}
+	b.reschedule()
+}
+
+// setPaused pauses or resumes the bucket.
+func (b *bucket) setPaused(paused bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.paused = paused
+	b.reschedule()
+}
+
+// reschedule makes waiting requests recompute their delays after a change of
+// rate or pause: the dispatch timer is reset and the turn holder woken up.
+// The caller must hold b.mu.
+func (b *bucket) reschedule() {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	close(b.wake)
+	b.wake = make(chan struct{})
+	b.dispatch()
+}
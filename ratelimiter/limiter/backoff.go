@@ -0,0 +1,104 @@
+package limiter
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxBackoffLevel caps the interval multiplier at 2^maxBackoffLevel.
+	maxBackoffLevel = 6
+	// recoverySuccesses is the number of consecutive successful responses
+	// needed to step the backoff level down by one.
+	recoverySuccesses = 3
+	// maxRetryAfter caps how long a single Retry-After header can block a host.
+	maxRetryAfter = time.Hour
+)
+
+// isPushback reports whether the status code means the remote site wants us
+// to slow down.
+func isPushback(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable
+}
+
+// parseRetryAfter parses a Retry-After header given either as a number of
+// seconds or as an HTTP date. It returns 0 if the header is absent or invalid.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	var d time.Duration
+	if secs, err := strconv.Atoi(value); err == nil {
+		d = time.Duration(secs) * time.Second
+	} else if t, err := http.ParseTime(value); err == nil {
+		d = t.Sub(now)
+	}
+
+	if d < 0 {
+		return 0
+	}
+	if d > maxRetryAfter {
+		return maxRetryAfter
+	}
+	return d
+}
+
+// effectiveInterval returns the bucket's interval, raised to the host's
+// Crawl-delay if that is longer, and widened by the current backoff level.
+func (b *bucket) effectiveInterval() time.Duration {
+	interval := b.interval
+	if b.crawlDelay > interval {
+		interval = b.crawlDelay
+	}
+	return interval << b.backoffLevel
+}
+
+// effectiveBurst returns the bucket's capacity. Bursts are disabled while the
+// host is backing off.
+func (b *bucket) effectiveBurst() int {
+	if b.backoffLevel > 0 {
+		return 1
+	}
+	return b.burst
+}
+
+// observe adjusts the backoff state after a response from the host. A 429 or
+// 503 raises the backoff level and blocks the host until the Retry-After time
+// (or one effective interval if the header is missing). Other responses count
+// towards stepping the level back down.
+func (b *bucket) observe(statusCode int, header http.Header, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !isPushback(statusCode) {
+		if b.backoffLevel > 0 {
+			b.successes++
+			if b.successes >= recoverySuccesses {
+				b.backoffLevel--
+				b.successes = 0
+			}
+			b.notifyChanged()
+		}
+		return
+	}
+	defer b.notifyChanged()
+
+	b.successes = 0
+	if b.backoffLevel < maxBackoffLevel {
+		b.backoffLevel++
+	}
+	b.tokens = 0
+	b.lastRefill = now
+
+	wait := parseRetryAfter(header.Get("Retry-After"), now)
+	if wait == 0 {
+		wait = b.effectiveInterval()
+	}
+	if until := now.Add(wait); until.After(b.blockedUntil) {
+		b.blockedUntil = until
+	}
+}
@@ -0,0 +1,86 @@
+package limiter
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 12, 6, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{"empty", "", 0},
+		{"seconds", "120", 2 * time.Minute},
+		{"seconds with spaces", " 5 ", 5 * time.Second},
+		{"zero", "0", 0},
+		{"negative", "-10", 0},
+		{"http date", "Sat, 06 Dec 2025 10:00:30 GMT", 30 * time.Second},
+		{"http date in the past", "Sat, 06 Dec 2025 09:59:00 GMT", 0},
+		{"capped", "86400", maxRetryAfter},
+		{"invalid", "soon", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryAfter(tt.value, now); got != tt.want {
+				t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestObserveBackoff(t *testing.T) {
+	now := time.Date(2025, 12, 6, 10, 0, 0, 0, time.UTC)
+	b := newBucket("example.com", time.Second, 3)
+
+	// A 429 with Retry-After blocks the host until then and disables bursts
+	b.observe(http.StatusTooManyRequests, http.Header{"Retry-After": {"30"}}, now)
+	if b.backoffLevel != 1 {
+		t.Fatalf("backoff level after 429 = %d, want 1", b.backoffLevel)
+	}
+	if want := now.Add(30 * time.Second); !b.blockedUntil.Equal(want) {
+		t.Errorf("blocked until %v, want %v", b.blockedUntil, want)
+	}
+	if got := b.effectiveInterval(); got != 2*time.Second {
+		t.Errorf("effective interval = %v, want 2s", got)
+	}
+	if got := b.effectiveBurst(); got != 1 {
+		t.Errorf("effective burst = %d, want 1", got)
+	}
+
+	// A 503 without Retry-After blocks for one effective interval
+	later := now.Add(time.Minute)
+	b.observe(http.StatusServiceUnavailable, http.Header{}, later)
+	if b.backoffLevel != 2 {
+		t.Fatalf("backoff level after 503 = %d, want 2", b.backoffLevel)
+	}
+	if want := later.Add(4 * time.Second); !b.blockedUntil.Equal(want) {
+		t.Errorf("blocked until %v, want %v", b.blockedUntil, want)
+	}
+
+	// Every recoverySuccesses successes step the level down by one
+	for i := 0; i < 2*recoverySuccesses; i++ {
+		b.observe(http.StatusOK, http.Header{}, later)
+	}
+	if b.backoffLevel != 0 {
+		t.Errorf("backoff level after recovery = %d, want 0", b.backoffLevel)
+	}
+	if got := b.effectiveBurst(); got != 3 {
+		t.Errorf("effective burst after recovery = %d, want 3", got)
+	}
+}
+
+func TestObserveBackoffCapped(t *testing.T) {
+	now := time.Date(2025, 12, 6, 10, 0, 0, 0, time.UTC)
+	b := newBucket("example.com", time.Second, 1)
+	for i := 0; i < maxBackoffLevel+3; i++ {
+		b.observe(http.StatusTooManyRequests, http.Header{}, now)
+	}
+	if b.backoffLevel != maxBackoffLevel {
+		t.Errorf("backoff level = %d, want %d", b.backoffLevel, maxBackoffLevel)
+	}
+}
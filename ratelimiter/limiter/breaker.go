@@ -0,0 +1,241 @@
+package limiter
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrCircuitOpen is returned without contacting the host while its circuit
+// breaker is open.
+var ErrCircuitOpen = errors.New("circuit open")
+
+// Circuit states reported in CircuitStatus.
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+// CircuitError reports a request refused because the host's circuit is
+// open. It matches ErrCircuitOpen.
+type CircuitError struct {
+	Host string
+	// RetryAt is when the circuit lets a probe request through.
+	RetryAt time.Time
+}
+
+func (e *CircuitError) Error() string {
+	return fmt.Sprintf("%v: %s has failed repeatedly, retrying at %s", ErrCircuitOpen, e.Host, e.RetryAt.Format(time.RFC3339))
+}
+
+func (e *CircuitError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// CircuitStatus is a point-in-time view of a host's circuit breaker.
+type CircuitStatus struct {
+	Host                string     `json:"host"`
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+	RetryAt             *time.Time `json:"retry_at,omitempty"`
+}
+
+// breaker is a host's circuit breaker. It opens after threshold consecutive
+// failures, i.e. network errors and 5xx responses. While it is open,
+// requests fail at once with a CircuitError instead of waiting for a token
+// and the client timeout. After cooldown it is half-open: a single probe
+// request is let through, which closes the circuit if it succeeds and opens
+// it again if it fails. A threshold of 0 disables the breaker.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	state     string
+	failures  int
+	lastError string
+	openedAt  time.Time
+	probing   bool
+}
+
+// newBreaker creates a closed breaker.
+func newBreaker(threshold int, cooldown time.Duration) breaker {
+	return breaker{threshold: threshold, cooldown: cooldown, state: CircuitClosed}
+}
+
+// circuitError returns the error for a request refused now.
+func (b *bucket) circuitError(now time.Time) error {
+	retryAt := b.breaker.openedAt.Add(b.breaker.cooldown)
+	if retryAt.Before(now) {
+		// Waiting for the probe in flight
+		retryAt = now
+	}
+	return &CircuitError{Host: b.host, RetryAt: retryAt}
+}
+
+// checkCircuit fails with a CircuitError if the circuit refuses a request
+// now: while it is open and cooling down, or while another request is
+// probing. probe is set for the request holding the probe, which is refused
+// only if the circuit has opened again.
+func (b *bucket) checkCircuit(now time.Time, probe bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.breaker.state {
+	case CircuitOpen:
+		if probe || now.Before(b.breaker.openedAt.Add(b.breaker.cooldown)) {
+			return b.circuitError(now)
+		}
+	case CircuitHalfOpen:
+		if !probe && b.breaker.probing {
+			return b.circuitError(now)
+		}
+	}
+	return nil
+}
+
+// admitCircuit lets a request through the circuit, or fails with a
+// CircuitError. Once the cooldown is over, the first request admitted
+// becomes the probe and is reported as such; it must call endProbe when it
+// is done. The caller must hold the bucket's turn.
+func (b *bucket) admitCircuit(now time.Time) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.breaker.state {
+	case CircuitOpen:
+		if now.Before(b.breaker.openedAt.Add(b.breaker.cooldown)) {
+			return false, b.circuitError(now)
+		}
+		b.breaker.state = CircuitHalfOpen
+		fallthrough
+	case CircuitHalfOpen:
+		if b.breaker.probing {
+			return false, b.circuitError(now)
+		}
+		b.breaker.probing = true
+		return true, nil
+	}
+	return false, nil
+}
+
+// endProbe lets another request probe if the probe ended without an answer
+// from the host, e.g. because its client gave up.
+func (b *bucket) endProbe() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.breaker.state == CircuitHalfOpen {
+		b.breaker.probing = false
+	}
+}
+
+// observeOutcome updates the circuit after a request to the host. err is
+// the network error, if any, and must be one hostFailure accepts; otherwise
+// a 5xx status counts as a failure.
+func (b *bucket) observeOutcome(statusCode int, err error, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	br := &b.breaker
+	if br.threshold <= 0 {
+		return
+	}
+
+	if err == nil && statusCode < 500 {
+		if br.state != CircuitClosed {
+			log.Printf("Circuit for %s closed: the host answered with status %d", b.host, statusCode)
+		}
+		br.state = CircuitClosed
+		br.failures = 0
+		br.lastError = ""
+		br.probing = false
+		return
+	}
+
+	br.failures++
+	if err != nil {
+		br.lastError = err.Error()
+	} else {
+		br.lastError = fmt.Sprintf("status %d", statusCode)
+	}
+	switch {
+	case br.state == CircuitHalfOpen:
+		log.Printf("Circuit for %s opened again: the probe failed (%s)", b.host, br.lastError)
+	case br.state == CircuitClosed && br.failures >= br.threshold:
+		log.Printf("Circuit for %s opened after %d consecutive failures (%s)", b.host, br.failures, br.lastError)
+	default:
+		// Still closed, or a late failure while already open
+		return
+	}
+	br.state = CircuitOpen
+	br.openedAt = now
+	br.probing = false
+}
+
+// hostFailure reports whether a request error says the host is down or
+// unreachable: a failed dial or DNS lookup, a timeout, a reset or dropped
+// connection, or a failed TLS handshake. Errors the request itself caused,
+// such as an invalid header value, say nothing about the host and must not
+// open its circuit.
+func hostFailure(err error) bool {
+	// *url.Error is a net.Error itself; look at what it wraps
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+
+	var netErr net.Error
+	var recordErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	var certErr *tls.CertificateVerificationError
+	switch {
+	case errors.As(err, &netErr):
+		// Includes *net.OpError for dials and resets and *net.DNSError
+		return true
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED):
+		return true
+	case errors.As(err, &recordErr), errors.As(err, &alertErr), errors.As(err, &certErr):
+		return true
+	}
+	return false
+}
+
+// circuitStatus returns the state of the bucket's circuit breaker.
+func (b *bucket) circuitStatus() CircuitStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := CircuitStatus{
+		Host:                b.host,
+		State:               b.breaker.state,
+		ConsecutiveFailures: b.breaker.failures,
+		LastError:           b.breaker.lastError,
+	}
+	if s.State != CircuitClosed {
+		openedAt := b.breaker.openedAt
+		retryAt := openedAt.Add(b.breaker.cooldown)
+		s.OpenedAt = &openedAt
+		s.RetryAt = &retryAt
+	}
+	return s
+}
+
+// Circuits returns the circuit breaker state of every host seen so far,
+// sorted by host.
+func (r *RateLimiter) Circuits() []CircuitStatus {
+	buckets := r.sortedBuckets()
+	circuits := make([]CircuitStatus, len(buckets))
+	for i, b := range buckets {
+		circuits[i] = b.circuitStatus()
+	}
+	return circuits
+}
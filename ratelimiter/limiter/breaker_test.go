@@ -0,0 +1,74 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestHostFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"dial refused", &url.Error{Op: "Get", URL: "http://example.com/", Err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}}, true},
+		{"dns", &url.Error{Op: "Get", URL: "http://example.com/", Err: &net.DNSError{Err: "no such host", Name: "example.com", IsNotFound: true}}, true},
+		{"connection reset", &url.Error{Op: "Get", URL: "http://example.com/", Err: fmt.Errorf("read: %w", syscall.ECONNRESET)}, true},
+		{"dropped connection", &url.Error{Op: "Get", URL: "http://example.com/", Err: io.EOF}, true},
+		{"invalid header", &url.Error{Op: "Post", URL: "http://example.com/", Err: errors.New(`net/http: invalid header field value for "X-Note"`)}, false},
+		{"too many redirects", &url.Error{Op: "Get", URL: "http://example.com/", Err: errors.New("stopped after 10 redirects")}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hostFailure(tt.err); got != tt.want {
+				t.Errorf("hostFailure(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBreakerIgnoresRejectedRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	rl := New(Policy{DefaultInterval: time.Millisecond, AllowPrivate: true, BreakerThreshold: 2, BreakerCooldown: time.Minute})
+	u, host, err := rl.parseTarget(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := rl.bucketFor(host)
+
+	// The transport refuses these before contacting the host
+	bad := http.Header{"X-Note": {"a\r\nb"}}
+	for i := 0; i < 3; i++ {
+		if _, err := rl.get(context.Background(), b, http.MethodPost, u.String(), nil, 0, bad); err == nil {
+			t.Fatal("request with an invalid header value was sent")
+		}
+	}
+	if c := b.circuitStatus(); c.State != CircuitClosed || c.ConsecutiveFailures != 0 {
+		t.Fatalf("circuit %s with %d failures after rejected requests, want closed with 0", c.State, c.ConsecutiveFailures)
+	}
+	if _, err := rl.Fetch(context.Background(), Request{URL: server.URL}); err != nil {
+		t.Errorf("fetch after rejected requests: %v", err)
+	}
+
+	// A host that is down still opens the circuit
+	server.Close()
+	for i := 0; i < 2; i++ {
+		rl.get(context.Background(), b, http.MethodGet, u.String(), nil, 0, nil)
+	}
+	if c := b.circuitStatus(); c.State != CircuitOpen {
+		t.Errorf("circuit %s after the host went down, want open", c.State)
+	}
+}
@@ -0,0 +1,185 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// bucket tracks the rate limit state for a single host as a token bucket.
+// A bucket holds at most burst tokens and earns one token per interval.
+// With a burst of 1 this is exactly "at most one request per interval".
+type bucket struct {
+	host       string
+	interval   time.Duration
+	burst      int
+	tokens     float64
+	lastRefill time.Time
+	lastFetch  time.Time
+	crawlDelay time.Duration
+
+	// Backoff state, see backoff.go.
+	backoffLevel int
+	successes    int
+	blockedUntil time.Time
+
+	// Circuit breaker state, see breaker.go.
+	breaker breaker
+
+	// The turn is held by the request about to take a token; the others
+	// wait in queue until timer fires, see queue.go.
+	holder *waiter
+	queue  []*waiter
+	timer  *time.Timer
+
+	// The turns are shared between clients by weight, see clients.go.
+	fair         *fairShare
+	clientWeight func(client string) float64
+
+	// While paused no token is handed out. wake is closed and replaced when
+	// the pause or the rate changes, see admin.go.
+	paused bool
+	wake   chan struct{}
+
+	// changed is signalled when the state worth saving changes, see state.go.
+	changed chan<- struct{}
+
+	// mu guards the fields above and is never held while sleeping.
+	mu sync.Mutex
+}
+
+// HostStatus is a point-in-time view of a host's rate limit state.
+type HostStatus struct {
+	Host                     string     `json:"host"`
+	IntervalSeconds          float64    `json:"interval_seconds"`
+	CrawlDelaySeconds        float64    `json:"crawl_delay_seconds,omitempty"`
+	EffectiveIntervalSeconds float64    `json:"effective_interval_seconds"`
+	Burst                    int        `json:"burst"`
+	Tokens                   float64    `json:"tokens"`
+	BackoffLevel             int        `json:"backoff_level"`
+	BlockedUntil             *time.Time `json:"blocked_until,omitempty"`
+	LastFetch                *time.Time `json:"last_fetch,omitempty"`
+}
+
+// newBucket creates a full bucket for the given host.
+func newBucket(host string, interval time.Duration, burst int) *bucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &bucket{
+		host:       host,
+		interval:   interval,
+		burst:      burst,
+		tokens:     float64(burst),
+		lastRefill: time.Now(),
+		wake:       make(chan struct{}),
+
+		fair:         newFairShare(),
+		clientWeight: func(string) float64 { return 1 },
+	}
+}
+
+// refill adds the tokens earned since the last refill, up to the burst size.
+// The caller must hold b.mu.
+func (b *bucket) refill(now time.Time) {
+	interval := b.effectiveInterval()
+	burst := float64(b.effectiveBurst())
+	if interval > 0 {
+		b.tokens += float64(now.Sub(b.lastRefill)) / float64(interval)
+	} else {
+		b.tokens = burst
+	}
+	if b.tokens > burst {
+		b.tokens = burst
+	}
+	b.lastRefill = now
+}
+
+// setCrawlDelay sets the minimum interval required by the host's robots.txt.
+func (b *bucket) setCrawlDelay(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.crawlDelay = d
+}
+
+// delay returns how long until a request may be sent to the host: until a
+// Retry-After block expires and a whole token is available. The caller must
+// hold b.mu.
+func (b *bucket) delay(now time.Time) time.Duration {
+	if now.Before(b.blockedUntil) {
+		return b.blockedUntil.Sub(now)
+	}
+	b.refill(now)
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) * float64(b.effectiveInterval()))
+}
+
+// wait blocks until a request may be sent to the host, consumes a token and
+// records the current time as the last fetch. If ctx is done first, no token
+// is consumed. The caller must hold the bucket's turn.
+func (b *bucket) wait(ctx context.Context) (time.Time, error) {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		d := b.delay(now)
+		if d <= 0 && !b.paused {
+			b.tokens--
+			b.lastFetch = now
+			b.notifyChanged()
+			b.mu.Unlock()
+			return now, nil
+		}
+		paused, wake := b.paused, b.wake
+		b.mu.Unlock()
+
+		// While paused, only Resume lets the request go
+		var timer *time.Timer
+		var expired <-chan time.Time
+		if !paused {
+			timer = time.NewTimer(d)
+			expired = timer.C
+		}
+		select {
+		case <-expired:
+		case <-wake:
+		case <-ctx.Done():
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return time.Time{}, ctx.Err()
+		}
+	}
+}
+
+// status returns the bucket's current state. It does not wait for a request
+// in progress.
+func (b *bucket) status() HostStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.refill(now)
+
+	s := HostStatus{
+		Host:                     b.host,
+		IntervalSeconds:          b.interval.Seconds(),
+		CrawlDelaySeconds:        b.crawlDelay.Seconds(),
+		EffectiveIntervalSeconds: b.effectiveInterval().Seconds(),
+		Burst:                    b.effectiveBurst(),
+		Tokens:                   b.tokens,
+		BackoffLevel:             b.backoffLevel,
+	}
+	if b.blockedUntil.After(now) {
+		t := b.blockedUntil
+		s.BlockedUntil = &t
+	}
+	if !b.lastFetch.IsZero() {
+		t := b.lastFetch
+		s.LastFetch = &t
+	}
+	return s
+}
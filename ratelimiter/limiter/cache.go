@@ -0,0 +1,102 @@
+package limiter
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// cacheEntry is the last 200 response seen for a URL.
+type cacheEntry struct {
+	url        string
+	finalURL   string
+	body       []byte
+	header     http.Header
+	statusCode int
+	fetchedAt  time.Time
+	element    *list.Element
+}
+
+// responseCache keeps the most recently used responses by URL, up to a
+// fixed number of entries and, if maxBytes is set, a total body size.
+type responseCache struct {
+	capacity int
+	maxBytes int64
+	size     int64
+	entries  map[string]*cacheEntry
+	order    *list.List // front is most recently used
+	mu       sync.Mutex
+}
+
+// newResponseCache creates a cache holding up to capacity entries whose
+// bodies add up to at most maxBytes (0 means no byte limit).
+func newResponseCache(capacity int, maxBytes int64) *responseCache {
+	return &responseCache{
+		capacity: capacity,
+		maxBytes: maxBytes,
+		entries:  make(map[string]*cacheEntry),
+		order:    list.New(),
+	}
+}
+
+// get returns a copy of the entry for the URL, if any.
+func (c *responseCache) get(url string) (cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[url]
+	if !ok {
+		return cacheEntry{}, false
+	}
+	c.order.MoveToFront(e.element)
+	copied := *e
+	copied.header = e.header.Clone()
+	return copied, true
+}
+
+// put stores a response, evicting the least recently used entries while the
+// cache is over its entry or byte limit. A body larger than the byte limit
+// is not cached at all.
+func (c *responseCache) put(e cacheEntry) {
+	if c.capacity <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if old, ok := c.entries[e.url]; ok {
+		c.remove(old)
+	}
+	if c.maxBytes > 0 && int64(len(e.body)) > c.maxBytes {
+		return
+	}
+	entry := &e
+	entry.element = c.order.PushFront(entry)
+	c.entries[e.url] = entry
+	c.size += int64(len(e.body))
+
+	for c.order.Len() > c.capacity || (c.maxBytes > 0 && c.size > c.maxBytes) {
+		c.remove(c.order.Back().Value.(*cacheEntry))
+	}
+}
+
+// remove drops an entry. The caller must hold c.mu.
+func (c *responseCache) remove(e *cacheEntry) {
+	c.order.Remove(e.element)
+	delete(c.entries, e.url)
+	c.size -= int64(len(e.body))
+}
+
+// conditionalHeaders returns the validators to send when revalidating the entry.
+func (e cacheEntry) conditionalHeaders() http.Header {
+	h := http.Header{}
+	if etag := e.header.Get("ETag"); etag != "" {
+		h.Set("If-None-Match", etag)
+	}
+	if lm := e.header.Get("Last-Modified"); lm != "" {
+		h.Set("If-Modified-Since", lm)
+	}
+	return h
+}
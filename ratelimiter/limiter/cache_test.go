@@ -0,0 +1,48 @@
+package limiter
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func cached(url string, size int) cacheEntry {
+	return cacheEntry{url: url, body: []byte(strings.Repeat("x", size)), header: http.Header{}}
+}
+
+func TestResponseCacheLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity int
+		maxBytes int64
+		puts     []cacheEntry
+		want     []string
+		size     int64
+	}{
+		{"entry limit", 2, 0, []cacheEntry{cached("a", 1), cached("b", 1), cached("c", 1)}, []string{"b", "c"}, 2},
+		{"byte limit", 10, 100, []cacheEntry{cached("a", 40), cached("b", 40), cached("c", 40)}, []string{"b", "c"}, 80},
+		{"too large", 10, 100, []cacheEntry{cached("a", 40), cached("b", 101)}, []string{"a"}, 40},
+		{"replaced by too large", 10, 100, []cacheEntry{cached("a", 40), cached("a", 101)}, nil, 0},
+		{"replaced", 10, 100, []cacheEntry{cached("a", 40), cached("a", 60), cached("b", 40)}, []string{"a", "b"}, 100},
+		{"disabled", 0, 100, []cacheEntry{cached("a", 1)}, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newResponseCache(tt.capacity, tt.maxBytes)
+			for _, e := range tt.puts {
+				c.put(e)
+			}
+			if len(c.entries) != len(tt.want) {
+				t.Errorf("cached %d entries, want %d", len(c.entries), len(tt.want))
+			}
+			for _, url := range tt.want {
+				if _, ok := c.get(url); !ok {
+					t.Errorf("%s is not cached", url)
+				}
+			}
+			if c.size != tt.size {
+				t.Errorf("size = %d, want %d", c.size, tt.size)
+			}
+		})
+	}
+}
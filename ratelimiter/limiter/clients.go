@@ -0,0 +1,291 @@
+package limiter
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrQuotaExceeded is returned for a request from a client that has used up
+// its daily quota.
+var ErrQuotaExceeded = errors.New("daily quota exceeded")
+
+// AnonymousClient identifies requests that carry no client ID.
+const AnonymousClient = "anonymous"
+
+// OtherClients is the entry shared by every client without its own weight
+// or quota. Client IDs come from the caller, so tracking each one would let
+// any caller grow the usage table and the metrics without bound, and dodge
+// the default quota by changing its ID.
+const OtherClients = "other"
+
+// QuotaError reports a client over its daily quota. It matches
+// ErrQuotaExceeded.
+type QuotaError struct {
+	Client  string
+	Quota   int
+	ResetAt time.Time
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%v: client %s has used its %d requests for today (resets at %s)", ErrQuotaExceeded, e.Client, e.Quota, e.ResetAt.Format(time.RFC3339))
+}
+
+func (e *QuotaError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// ClientStatus is a client's usage for the status endpoint.
+type ClientStatus struct {
+	Client string  `json:"client"`
+	Weight float64 `json:"weight"`
+	// Quota is the number of requests allowed per day, 0 for no limit.
+	Quota     int       `json:"quota"`
+	UsedToday int       `json:"used_today"`
+	Remaining *int      `json:"remaining,omitempty"`
+	ResetsAt  time.Time `json:"resets_at"`
+	Requests  int64     `json:"requests_total"`
+	Rejected  int64     `json:"rejected_total"`
+	Queued    int       `json:"queued"`
+}
+
+// clientUsage counts a client's requests. used is reset when day changes.
+// A request is counted in used when it passes the quota check and taken off
+// again if its fetch fails, so concurrent requests cannot overshoot.
+type clientUsage struct {
+	day      string
+	used     int
+	requests int64
+	rejected int64
+}
+
+// clients tracks the usage of AnonymousClient, OtherClients and the clients
+// the policy names.
+type clients struct {
+	usage map[string]*clientUsage
+	mu    sync.Mutex
+}
+
+// newClients creates an empty client registry.
+func newClients() *clients {
+	return &clients{usage: make(map[string]*clientUsage)}
+}
+
+// clientID returns the client of a request, AnonymousClient if it has none.
+func (req Request) clientID() string {
+	if client := strings.TrimSpace(req.Client); client != "" {
+		return client
+	}
+	return AnonymousClient
+}
+
+// clientKey returns the entry the client is queued, limited and counted
+// under: its own for AnonymousClient and the clients with a weight or quota,
+// OtherClients for everyone else.
+func (p Policy) clientKey(client string) string {
+	if client == AnonymousClient {
+		return client
+	}
+	if _, ok := p.ClientWeights[client]; ok {
+		return client
+	}
+	if _, ok := p.ClientQuotas[client]; ok {
+		return client
+	}
+	return OtherClients
+}
+
+// clientFor returns the entry of the request's client, see clientKey.
+func (r *RateLimiter) clientFor(req Request) string {
+	return r.currentPolicy().clientKey(req.clientID())
+}
+
+// Weight returns the client's share of each host's turns relative to the
+// other clients, 1 unless the policy says otherwise.
+func (p Policy) Weight(client string) float64 {
+	if w, ok := p.ClientWeights[client]; ok && w > 0 {
+		return w
+	}
+	return 1
+}
+
+// Quota returns the client's daily request quota, 0 for no limit.
+func (p Policy) Quota(client string) int {
+	if q, ok := p.ClientQuotas[client]; ok {
+		return q
+	}
+	return p.DefaultQuota
+}
+
+// quotaDay returns the UTC day a quota period belongs to and when it ends.
+func quotaDay(now time.Time) (string, time.Time) {
+	now = now.UTC()
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return start.Format("2006-01-02"), start.AddDate(0, 0, 1)
+}
+
+// lookup returns the client's usage, starting a new day if needed. The
+// caller must hold c.mu.
+func (c *clients) lookup(client string, now time.Time) *clientUsage {
+	day, _ := quotaDay(now)
+	u, ok := c.usage[client]
+	if !ok {
+		u = &clientUsage{day: day}
+		c.usage[client] = u
+	}
+	if u.day != day {
+		u.day = day
+		u.used = 0
+	}
+	return u
+}
+
+// check fails with a QuotaError, counting the rejection, if the client has
+// no quota left. If count is set, a request that passes is counted against
+// the quota until refund takes it back.
+func (c *clients) check(client string, quota int, count bool, now time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	u := c.lookup(client, now)
+	if quota > 0 && u.used >= quota {
+		u.rejected++
+		_, resetAt := quotaDay(now)
+		return &QuotaError{Client: client, Quota: quota, ResetAt: resetAt}
+	}
+	if count {
+		u.used++
+		u.requests++
+	}
+	return nil
+}
+
+// refund takes back a request counted by check at countedAt whose fetch
+// failed, unless the quota day has turned since.
+func (c *clients) refund(client string, countedAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	day, _ := quotaDay(countedAt)
+	u, ok := c.usage[client]
+	if !ok || u.day != day {
+		return
+	}
+	if u.used > 0 {
+		u.used--
+	}
+	if u.requests > 0 {
+		u.requests--
+	}
+}
+
+// Clients returns the usage of AnonymousClient and OtherClients if seen,
+// and of every client with its own weight or quota, sorted by client ID.
+func (r *RateLimiter) Clients() []ClientStatus {
+	policy := r.currentPolicy()
+	now := time.Now()
+	_, resetAt := quotaDay(now)
+
+	queued := make(map[string]int)
+	for _, q := range r.Queue() {
+		for _, e := range q.Entries {
+			queued[e.Client]++
+		}
+	}
+
+	r.clients.mu.Lock()
+	ids := make(map[string]bool, len(r.clients.usage))
+	for client := range r.clients.usage {
+		ids[client] = true
+	}
+	for client := range policy.ClientWeights {
+		ids[client] = true
+	}
+	for client := range policy.ClientQuotas {
+		ids[client] = true
+	}
+	for client := range queued {
+		ids[client] = true
+	}
+
+	statuses := make([]ClientStatus, 0, len(ids))
+	for client := range ids {
+		u := r.clients.lookup(client, now)
+		s := ClientStatus{
+			Client:    client,
+			Weight:    policy.Weight(client),
+			Quota:     policy.Quota(client),
+			UsedToday: u.used,
+			ResetsAt:  resetAt,
+			Requests:  u.requests,
+			Rejected:  u.rejected,
+			Queued:    queued[client],
+		}
+		if s.Quota > 0 {
+			remaining := s.Quota - u.used
+			if remaining < 0 {
+				remaining = 0
+			}
+			s.Remaining = &remaining
+		}
+		statuses = append(statuses, s)
+	}
+	r.clients.mu.Unlock()
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Client < statuses[j].Client })
+	return statuses
+}
+
+// clientWeight returns the client's weight under the current policy.
+func (r *RateLimiter) clientWeight(client string) float64 {
+	return r.currentPolicy().Weight(client)
+}
+
+// fairShare tracks how many of a bucket's turns each client has had, as in
+// start-time fair queuing: every turn advances the client's tag by
+// 1/weight, and the client with the lowest tag goes next. A client that was
+// idle starts at the tag of the last turn granted, so it cannot save up
+// turns while others are busy.
+type fairShare struct {
+	tags map[string]float64
+	now  float64
+}
+
+// newFairShare creates a fairShare in which every client is even.
+func newFairShare() *fairShare {
+	return &fairShare{tags: make(map[string]float64)}
+}
+
+// tag returns the client's tag; lower goes first.
+func (f *fairShare) tag(client string) float64 {
+	if t := f.tags[client]; t > f.now {
+		return t
+	}
+	return f.now
+}
+
+// charge records a turn granted to the client.
+func (f *fairShare) charge(client string, weight float64) {
+	start := f.tag(client)
+	f.now = start
+	f.tags[client] = start + 1/weight
+
+	// Clients at or behind the virtual time are even again
+	for c, t := range f.tags {
+		if t <= f.now {
+			delete(f.tags, c)
+		}
+	}
+}
+
+// clone returns a copy for simulating the order of the queue.
+func (f *fairShare) clone() *fairShare {
+	c := &fairShare{tags: make(map[string]float64, len(f.tags)), now: f.now}
+	for client, t := range f.tags {
+		c.tags[client] = t
+	}
+	return c
+}
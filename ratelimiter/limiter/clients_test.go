@@ -0,0 +1,109 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClientKey(t *testing.T) {
+	policy := Policy{
+		ClientWeights: map[string]float64{"ui": 3},
+		ClientQuotas:  map[string]int{"parser": 100},
+	}
+	tests := []struct {
+		client string
+		want   string
+	}{
+		{AnonymousClient, AnonymousClient},
+		{"ui", "ui"},
+		{"parser", "parser"},
+		{"Parser", OtherClients},
+		{"random-1234", OtherClients},
+		{OtherClients, OtherClients},
+	}
+	for _, tt := range tests {
+		t.Run(tt.client, func(t *testing.T) {
+			if got := policy.clientKey(tt.client); got != tt.want {
+				t.Errorf("clientKey(%q) = %q, want %q", tt.client, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuotaChargesOnlyResults(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/image" {
+			w.Header().Set("Content-Type", "image/png")
+		} else {
+			w.Header().Set("Content-Type", "text/html")
+		}
+		w.Write([]byte("x"))
+	}))
+	defer server.Close()
+
+	rl := New(Policy{
+		DefaultInterval:     time.Millisecond,
+		AllowPrivate:        true,
+		AllowedContentTypes: []string{"text/html"},
+		ClientQuotas:        map[string]int{"parser": 2},
+		DefaultQuota:        1,
+	})
+	fetch := func(client, path string) error {
+		_, err := rl.Fetch(context.Background(), Request{URL: server.URL + path, Client: client})
+		return err
+	}
+
+	tests := []struct {
+		name   string
+		client string
+		path   string
+		want   error
+	}{
+		{"failed fetch is refunded", "parser", "/image", ErrContentType},
+		{"failed fetch is refunded again", "parser", "/image", ErrContentType},
+		{"first result", "parser", "/page", nil},
+		{"second result", "parser", "/page", nil},
+		{"over quota", "parser", "/page", ErrQuotaExceeded},
+		{"unknown client", "crawler-a", "/page", nil},
+		{"unknown clients share a quota", "crawler-b", "/page", ErrQuotaExceeded},
+	}
+	for _, tt := range tests {
+		if err := fetch(tt.client, tt.path); !errors.Is(err, tt.want) {
+			t.Fatalf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	used := make(map[string]int)
+	for _, c := range rl.Clients() {
+		used[c.Client] = c.UsedToday
+	}
+	want := map[string]int{"parser": 2, OtherClients: 1}
+	if len(used) != len(want) {
+		t.Errorf("clients = %v, want %v", used, want)
+	}
+	for client, n := range want {
+		if used[client] != n {
+			t.Errorf("%s used %d, want %d", client, used[client], n)
+		}
+	}
+}
+
+func TestRefundAfterDayChange(t *testing.T) {
+	c := newClients()
+	yesterday := time.Date(2025, 12, 5, 23, 59, 0, 0, time.UTC)
+	if err := c.check("parser", 1, true, yesterday); err != nil {
+		t.Fatal(err)
+	}
+	// Today's first request, then yesterday's fetch fails
+	if err := c.check("parser", 1, true, yesterday.Add(2*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	c.refund("parser", yesterday)
+	if used := c.usage["parser"].used; used != 1 {
+		t.Errorf("used = %d after refunding yesterday's request, want 1", used)
+	}
+}
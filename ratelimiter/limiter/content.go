@@ -0,0 +1,219 @@
+package limiter
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// ErrContentType is returned for a response whose content type is not in
+// Policy.AllowedContentTypes.
+var ErrContentType = errors.New("content type not allowed")
+
+// errUnknownCharset is returned by toUTF8 for a charset it cannot decode.
+var errUnknownCharset = errors.New("unsupported charset")
+
+// sniffLen is how much of a body is inspected for its type and charset.
+const sniffLen = 1024
+
+// metaCharset finds a charset declared in an HTML <meta> tag, either as
+// <meta charset="..."> or in an http-equiv Content-Type.
+var metaCharset = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?\s*([a-z0-9_:.\-]+)`)
+
+// decodeContent replaces the response body with a reader that undoes its
+// gzip or deflate Content-Encoding, and drops the headers describing the
+// encoded form, as the default transport does for gzip.
+func decodeContent(resp *http.Response) error {
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	if encoding == "" || encoding == "identity" {
+		return nil
+	}
+
+	// Bodies of 304s and errors may be empty despite the header
+	br := bufio.NewReader(resp.Body)
+	if _, err := br.Peek(1); err == io.EOF {
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{br, resp.Body}
+		return nil
+	}
+
+	var decoded io.Reader
+	switch encoding {
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return fmt.Errorf("failed to decode gzip response: %w", err)
+		}
+		decoded = gz
+	case "deflate":
+		// Usually zlib-wrapped as the spec says, sometimes raw
+		if header, _ := br.Peek(2); len(header) == 2 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 && header[0]&0x0f == 8 {
+			zr, err := zlib.NewReader(br)
+			if err != nil {
+				return fmt.Errorf("failed to decode deflate response: %w", err)
+			}
+			decoded = zr
+		} else {
+			decoded = flate.NewReader(br)
+		}
+	default:
+		return fmt.Errorf("unsupported Content-Encoding %q", encoding)
+	}
+
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{decoded, resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
+
+// mediaType returns the lower-case media type and charset parameter of a
+// Content-Type header. If the header is missing, the media type is sniffed
+// from the body and the charset left to detectCharset.
+func mediaType(header string, body []byte) (string, string) {
+	if header == "" {
+		mt, _, _ := strings.Cut(http.DetectContentType(body), ";")
+		return mt, ""
+	}
+	mt, params, err := mime.ParseMediaType(header)
+	if err != nil {
+		// Keep whatever precedes the parameters
+		mt, _, _ = strings.Cut(header, ";")
+		return strings.ToLower(strings.TrimSpace(mt)), ""
+	}
+	return mt, strings.ToLower(params["charset"])
+}
+
+// contentTypeAllowed reports whether mt matches one of the patterns, which
+// are media types or wildcards such as "text/*". No patterns allows all.
+func contentTypeAllowed(patterns []string, mt string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if pattern == mt || pattern == "*/*" {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok && strings.HasPrefix(mt, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// isText reports whether a body of the media type should be returned as text.
+func isText(mt string) bool {
+	switch {
+	case strings.HasPrefix(mt, "text/"):
+		return true
+	case strings.HasSuffix(mt, "+xml"), strings.HasSuffix(mt, "+json"):
+		return true
+	}
+	switch mt {
+	case "application/json", "application/xml", "application/javascript", "application/ecmascript", "application/x-www-form-urlencoded":
+		return true
+	}
+	return false
+}
+
+// setBody fills in the result's body fields: text bodies are converted to
+// UTF-8 and returned in HTML, anything else base64-encoded in BodyBase64.
+// Text in a charset that cannot be decoded is returned in BodyBase64 too,
+// with the reason in CharsetError.
+func (res *FetchResult) setBody(body []byte, contentType string) {
+	mt, charset := mediaType(contentType, body)
+	res.ContentType = mt
+	res.ContentLength = int64(len(body))
+
+	if !isText(mt) {
+		res.BodyBase64 = base64.StdEncoding.EncodeToString(body)
+		return
+	}
+	if charset == "" {
+		charset = detectCharset(body)
+	}
+	res.Charset = charset
+	text, err := toUTF8(body, charset)
+	if err != nil {
+		// Return the bytes untouched rather than guess at the text
+		res.CharsetError = err.Error()
+		res.BodyBase64 = base64.StdEncoding.EncodeToString(body)
+		return
+	}
+	res.HTML = text
+}
+
+// detectCharset guesses the charset of a body without a charset parameter:
+// a byte order mark, then a <meta> declaration, then UTF-8 if the body is
+// valid UTF-8, and Windows-1252 otherwise.
+func detectCharset(body []byte) string {
+	switch {
+	case bytes.HasPrefix(body, []byte{0xef, 0xbb, 0xbf}):
+		return "utf-8"
+	case bytes.HasPrefix(body, []byte{0xff, 0xfe}):
+		return "utf-16le"
+	case bytes.HasPrefix(body, []byte{0xfe, 0xff}):
+		return "utf-16be"
+	}
+
+	head := body
+	if len(head) > sniffLen {
+		head = head[:sniffLen]
+	}
+	// A declaration naming no known charset is ignored, as browsers do
+	if m := metaCharset.FindSubmatch(head); m != nil {
+		if label := strings.ToLower(string(m[1])); isKnownCharset(label) {
+			return label
+		}
+	}
+
+	if utf8.Valid(body) {
+		return "utf-8"
+	}
+	return "windows-1252"
+}
+
+// isKnownCharset reports whether toUTF8 can decode the charset.
+func isKnownCharset(charset string) bool {
+	_, err := htmlindex.Get(charset)
+	return err == nil
+}
+
+// toUTF8 converts body from the named charset, using the labels and
+// decoders of the WHATWG Encoding Standard as browsers do: ISO-8859-1 and
+// ASCII are decoded as Windows-1252, and a byte order mark overrides the
+// label. Invalid sequences become U+FFFD. It returns an error wrapping
+// errUnknownCharset if the label names no supported encoding.
+func toUTF8(body []byte, charset string) (string, error) {
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return "", fmt.Errorf("%w %q", errUnknownCharset, charset)
+	}
+	decoded, _, err := transform.Bytes(unicode.BOMOverride(enc.NewDecoder()), body)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode %s: %w", charset, err)
+	}
+	return string(decoded), nil
+}
@@ -0,0 +1,116 @@
+package limiter
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestToUTF8(t *testing.T) {
+	tests := []struct {
+		name    string
+		charset string
+		body    string
+		want    string
+	}{
+		{"utf-8", "utf-8", "café", "café"},
+		{"utf-8 bom", "utf-8", "\xef\xbb\xbfcafé", "café"},
+		{"invalid utf-8", "utf-8", "a\xffb", "a�b"},
+		{"windows-1252", "windows-1252", "caf\xe9 \x80", "café €"},
+		{"latin1 label", "iso-8859-1", "\x93quoted\x94", "“quoted”"},
+		{"ascii label", "us-ascii", "caf\xe9", "café"},
+		{"label case and spaces", " Windows-1252 ", "caf\xe9", "café"},
+		{"iso-8859-2", "iso-8859-2", "\xb1", "ą"},
+		{"koi8-r", "koi8-r", "\xf0\xd2\xc9\xd7\xc5\xd4", "Привет"},
+		{"shift_jis", "shift_jis", "\x82\xa0", "あ"},
+		{"gbk", "gb2312", "\xc4\xe3\xba\xc3", "你好"},
+		{"euc-kr", "euc-kr", "\xc7\xd1", "한"},
+		{"utf-16le bom", "utf-16le", "\xff\xfeh\x00i\x00", "hi"},
+		{"utf-16be", "utf-16be", "\x00h\x00i", "hi"},
+		{"utf-16 big-endian bom", "utf-16", "\xfe\xff\x00h\x00i", "hi"},
+		{"bom overrides label", "windows-1252", "\xef\xbb\xbfcaf\xc3\xa9", "café"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := toUTF8([]byte(tt.body), tt.charset)
+			if err != nil {
+				t.Fatalf("toUTF8(%q) error: %v", tt.charset, err)
+			}
+			if got != tt.want {
+				t.Errorf("toUTF8(%q) = %q, want %q", tt.charset, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToUTF8UnknownCharset(t *testing.T) {
+	if _, err := toUTF8([]byte("text"), "x-made-up"); !errors.Is(err, errUnknownCharset) {
+		t.Errorf("toUTF8 of an unknown charset = %v, want errUnknownCharset", err)
+	}
+}
+
+func TestDetectCharset(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"utf-8 bom", "\xef\xbb\xbf<p>x</p>", "utf-8"},
+		{"utf-16le bom", "\xff\xfe<\x00", "utf-16le"},
+		{"utf-16be bom", "\xfe\xff\x00<", "utf-16be"},
+		{"meta charset", `<meta charset="Shift_JIS"><p>x</p>`, "shift_jis"},
+		{"http-equiv", `<meta http-equiv="Content-Type" content="text/html; charset=koi8-r">`, "koi8-r"},
+		{"unknown meta ignored", "<meta charset=\"x-made-up\">caf\xe9", "windows-1252"},
+		{"valid utf-8", "<p>café</p>", "utf-8"},
+		{"invalid utf-8", "<p>caf\xe9</p>", "windows-1252"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectCharset([]byte(tt.body)); got != tt.want {
+				t.Errorf("detectCharset = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		html        string
+		charset     string
+		base64      bool
+		charsetErr  bool
+	}{
+		{"declared charset", "text/html; charset=ISO-8859-1", "caf\xe9", "café", "iso-8859-1", false, false},
+		{"detected charset", "text/html", "<meta charset=koi8-r>\xf0", "<meta charset=koi8-r>П", "koi8-r", false, false},
+		{"binary", "image/png", "\x89PNG", "", "", true, false},
+		{"unknown charset", "text/html; charset=x-made-up", "caf\xe9", "", "x-made-up", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var res FetchResult
+			res.setBody([]byte(tt.body), tt.contentType)
+			if res.HTML != tt.html {
+				t.Errorf("HTML = %q, want %q", res.HTML, tt.html)
+			}
+			if res.Charset != tt.charset {
+				t.Errorf("Charset = %q, want %q", res.Charset, tt.charset)
+			}
+			if (res.CharsetError != "") != tt.charsetErr {
+				t.Errorf("CharsetError = %q", res.CharsetError)
+			}
+			if !tt.base64 {
+				if res.BodyBase64 != "" {
+					t.Errorf("BodyBase64 = %q, want none", res.BodyBase64)
+				}
+				return
+			}
+			body, err := base64.StdEncoding.DecodeString(res.BodyBase64)
+			if err != nil || string(body) != tt.body {
+				t.Errorf("BodyBase64 decodes to %q, %v; want the raw body %q", body, err, tt.body)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package limiter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// flight is an upstream fetch shared by every caller asking for the same
+// request while it is in progress.
+type flight struct {
+	done    chan struct{}
+	result  *FetchResult
+	err     error
+	callers int
+	cancel  context.CancelFunc
+}
+
+// flightGroup deduplicates concurrent identical fetches.
+type flightGroup struct {
+	flights map[string]*flight
+	mu      sync.Mutex
+}
+
+// newFlightGroup creates an empty flightGroup.
+func newFlightGroup() *flightGroup {
+	return &flightGroup{flights: make(map[string]*flight)}
+}
+
+// do runs fn once for all concurrent callers with the same key and returns
+// its result to each of them, with Shared set if there was more than one.
+// fn runs on its own context, cancelled only when every caller has given up,
+// so one disconnecting client does not fail the others.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(context.Context) (*FetchResult, error)) (*FetchResult, error) {
+	g.mu.Lock()
+	f, ok := g.flights[key]
+	if !ok {
+		fctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		f = &flight{done: make(chan struct{}), cancel: cancel}
+		g.flights[key] = f
+		go func() {
+			f.result, f.err = fn(fctx)
+			cancel()
+
+			// Later callers start a new flight
+			g.mu.Lock()
+			if g.flights[key] == f {
+				delete(g.flights, key)
+			}
+			g.mu.Unlock()
+			close(f.done)
+		}()
+	}
+	f.callers++
+	g.mu.Unlock()
+
+	select {
+	case <-f.done:
+	case <-ctx.Done():
+		g.mu.Lock()
+		f.callers--
+		if f.callers == 0 {
+			// Nobody wants the result; don't let new callers join
+			f.cancel()
+			if g.flights[key] == f {
+				delete(g.flights, key)
+			}
+		}
+		g.mu.Unlock()
+		return nil, ctx.Err()
+	}
+
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	// The flight has left the map, so no caller can join any more
+	g.mu.Lock()
+	shared := f.callers > 1
+	g.mu.Unlock()
+
+	result := *f.result
+	result.Shared = shared
+	return &result, nil
+}
+
+// flightKey identifies requests that can share a fetch: same method, URL,
+// headers and body. Priority and MaxAge do not matter. Only requests that
+// are shareable get this far.
+func flightKey(req Request) string {
+	h := sha256.New()
+	h.Write([]byte(req.method() + " " + req.URL + "\n"))
+
+	keys := make([]string, 0, len(req.Header))
+	for key := range req.Header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range req.Header[key] {
+			h.Write([]byte(http.CanonicalHeaderKey(key) + ": " + value + "\n"))
+		}
+	}
+
+	h.Write([]byte("\n"))
+	h.Write(req.Body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,63 @@
+package limiter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetchSharesOnlySafeMethods(t *testing.T) {
+	var hits sync.Map
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := hits.LoadOrStore(r.Method, new(int32))
+		atomic.AddInt32(n.(*int32), 1)
+		// Long enough for the concurrent callers to overlap
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		method string
+		want   int32
+	}{
+		{http.MethodGet, 1},
+		{http.MethodHead, 1},
+		{http.MethodPost, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			rl := New(Policy{DefaultInterval: time.Millisecond, Burst: 3, AllowPrivate: true})
+
+			var wg sync.WaitGroup
+			var shared int32
+			for i := 0; i < 3; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					res, err := rl.Fetch(context.Background(), Request{URL: server.URL + "/form", Method: tt.method})
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					if res.Shared {
+						atomic.AddInt32(&shared, 1)
+					}
+				}()
+			}
+			wg.Wait()
+
+			n, _ := hits.LoadOrStore(tt.method, new(int32))
+			if got := atomic.LoadInt32(n.(*int32)); got != tt.want {
+				t.Errorf("%s reached the server %d times, want %d", tt.method, got, tt.want)
+			}
+			if tt.want > 1 && shared != 0 {
+				t.Errorf("%d %s results were marked shared", shared, tt.method)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package limiter
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrBlocked is returned for a URL refused by the policy's scheme, host or
+// address rules.
+var ErrBlocked = errors.New("URL blocked")
+
+// defaultSchemes are allowed when Policy.AllowedSchemes is empty.
+var defaultSchemes = []string{"http", "https"}
+
+// blockedPrefixes are special-purpose ranges refused in addition to the
+// loopback, private, link-local, multicast and unspecified addresses.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	// Local-use NAT64, whose IPv4 embedding depends on the network
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+}
+
+// ipv4Embeddings are IPv6 ranges that reach an IPv4 address embedded at
+// offset on networks that translate or tunnel them, so that address is
+// checked too.
+var ipv4Embeddings = []struct {
+	prefix netip.Prefix
+	offset int
+}{
+	{netip.MustParsePrefix("64:ff9b::/96"), 12},    // NAT64 well-known prefix
+	{netip.MustParsePrefix("::ffff:0:0:0/96"), 12}, // IPv4-translated
+	{netip.MustParsePrefix("::/96"), 12},           // IPv4-compatible
+	{netip.MustParsePrefix("2002::/16"), 2},        // 6to4
+}
+
+// blockedError explains why a URL was refused. It matches ErrBlocked.
+type blockedError struct {
+	url    string
+	reason string
+}
+
+func (e *blockedError) Error() string {
+	if e.url == "" {
+		return fmt.Sprintf("%v: %s", ErrBlocked, e.reason)
+	}
+	return fmt.Sprintf("%v: %s: %s", ErrBlocked, e.url, e.reason)
+}
+
+func (e *blockedError) Is(target error) bool {
+	return target == ErrBlocked
+}
+
+// Schemes returns the URL schemes that may be fetched.
+func (p Policy) Schemes() []string {
+	if len(p.AllowedSchemes) == 0 {
+		return append([]string(nil), defaultSchemes...)
+	}
+	return append([]string(nil), p.AllowedSchemes...)
+}
+
+// checkURL applies the policy's scheme and host rules to u, and refuses IP
+// literals in blocked ranges without waiting for a connection attempt.
+func (p Policy) checkURL(u *url.URL) error {
+	schemes := p.Schemes()
+	if !containsFold(schemes, u.Scheme) {
+		return &blockedError{url: u.String(), reason: fmt.Sprintf("scheme %q is not allowed (allowed: %s)", u.Scheme, strings.Join(schemes, ", "))}
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if matchHost(p.DeniedHosts, host) {
+		return &blockedError{url: u.String(), reason: fmt.Sprintf("host %s is denied", host)}
+	}
+	if len(p.AllowedHosts) > 0 && !matchHost(p.AllowedHosts, host) {
+		return &blockedError{url: u.String(), reason: fmt.Sprintf("host %s is not in the allowlist", host)}
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil && !p.AllowPrivate {
+		if kind := blockedKind(addr); kind != "" {
+			return &blockedError{url: u.String(), reason: fmt.Sprintf("%s is a %s address", addr, kind)}
+		}
+	}
+	return nil
+}
+
+// CheckURL parses rawURL and applies the policy's scheme, host and address
+// rules to it, for URLs the service contacts on a caller's behalf without
+// fetching them, such as job callbacks. The error matches ErrInvalidURL or
+// ErrBlocked.
+func (r *RateLimiter) CheckURL(rawURL string) error {
+	_, _, err := r.parseTarget(rawURL)
+	return err
+}
+
+// checkDial refuses connections to blocked addresses. It runs after DNS
+// resolution for every connection, including each redirect hop, so a host
+// name resolving to an internal address is caught too.
+func (r *RateLimiter) checkDial(network, address string, _ syscall.RawConn) error {
+	if r.currentPolicy().AllowPrivate {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return &blockedError{reason: fmt.Sprintf("invalid address %q", address)}
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return &blockedError{reason: fmt.Sprintf("invalid address %q", address)}
+	}
+	if kind := blockedKind(addr); kind != "" {
+		return &blockedError{reason: fmt.Sprintf("resolves to %s, a %s address", addr, kind)}
+	}
+	return nil
+}
+
+// blockedKind describes why addr may not be fetched, or returns "" if it may.
+// An IPv6 address embedding an IPv4 address is refused if either is.
+func blockedKind(addr netip.Addr) string {
+	addr = addr.Unmap()
+	switch {
+	case addr.IsLoopback():
+		return "loopback"
+	case addr.IsPrivate():
+		return "private"
+	case addr.IsLinkLocalUnicast(), addr.IsLinkLocalMulticast():
+		return "link-local"
+	case addr.IsUnspecified():
+		return "unspecified"
+	case addr.IsMulticast():
+		return "multicast"
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return "reserved"
+		}
+	}
+	if v4, ok := embeddedIPv4(addr); ok {
+		return blockedKind(v4)
+	}
+	return ""
+}
+
+// embeddedIPv4 returns the IPv4 address embedded in addr by NAT64, 6to4 or
+// the IPv4-compatible and IPv4-translated forms, if any.
+func embeddedIPv4(addr netip.Addr) (netip.Addr, bool) {
+	if !addr.Is6() {
+		return netip.Addr{}, false
+	}
+	b := addr.As16()
+	for _, e := range ipv4Embeddings {
+		if e.prefix.Contains(addr) {
+			return netip.AddrFrom4([4]byte(b[e.offset : e.offset+4])), true
+		}
+	}
+	return netip.Addr{}, false
+}
+
+// matchHost reports whether host equals one of the patterns or is a
+// subdomain of one.
+func matchHost(patterns []string, host string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimPrefix(pattern, "*."))
+		if host == pattern || strings.HasSuffix(host, "."+pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsFold reports whether list contains s, ignoring case.
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// newTransport returns a transport that dials through checkDial and ignores
+// proxy settings, since a proxy would hide the address actually fetched.
+func (r *RateLimiter) newTransport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = nil
+	t.DialContext = (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   r.checkDial,
+	}).DialContext
+	return t
+}
+
+// NewClient returns an HTTP client bound by the same rules as fetches, for
+// requests that bypass the rate limit such as job callbacks: connections go
+// through checkDial and redirect targets through checkURL.
+func (r *RateLimiter) NewClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Transport: r.newTransport(),
+		Timeout:   timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if err := r.currentPolicy().checkURL(req.URL); err != nil {
+				return err
+			}
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return nil
+		},
+	}
+}
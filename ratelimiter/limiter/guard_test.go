@@ -0,0 +1,73 @@
+package limiter
+
+import (
+	"errors"
+	"net/netip"
+	"net/url"
+	"testing"
+)
+
+func TestBlockedKind(t *testing.T) {
+	tests := []struct {
+		addr string
+		want string
+	}{
+		{"93.184.216.34", ""},
+		{"127.0.0.1", "loopback"},
+		{"10.1.2.3", "private"},
+		{"192.168.0.1", "private"},
+		{"169.254.169.254", "link-local"},
+		{"0.0.0.0", "unspecified"},
+		{"100.64.0.1", "reserved"},
+		{"239.1.1.1", "multicast"},
+		{"2606:2800:220:1:248:1893:25c8:1946", ""},
+		{"::1", "loopback"},
+		{"::", "unspecified"},
+		{"fd00::1", "private"},
+		{"fe80::1", "link-local"},
+		{"::ffff:127.0.0.1", "loopback"},
+		// NAT64, well-known and local-use prefixes
+		{"64:ff9b::7f00:1", "loopback"},
+		{"64:ff9b::a9fe:a9fe", "link-local"},
+		{"64:ff9b::5db8:d822", ""},
+		{"64:ff9b:1::5db8:d822", "reserved"},
+		// 6to4
+		{"2002:a00:1::1", "private"},
+		{"2002:7f00:1::", "loopback"},
+		{"2002:5db8:d822::1", ""},
+		// IPv4-compatible and IPv4-translated
+		{"::10.0.0.1", "private"},
+		{"::ffff:0:192.168.1.1", "private"},
+		{"::ffff:0:93.184.216.34", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if got := blockedKind(netip.MustParseAddr(tt.addr)); got != tt.want {
+				t.Errorf("blockedKind(%s) = %q, want %q", tt.addr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckURLEmbeddedIPv4(t *testing.T) {
+	tests := []struct {
+		url     string
+		private bool
+		blocked bool
+	}{
+		{"http://[64:ff9b::a00:1]/", false, true},
+		{"http://[2002:c0a8:101::1]/admin", false, true},
+		{"http://[::ffff:0:7f00:1]:8080/", false, true},
+		{"http://[64:ff9b::a00:1]/", true, false},
+		{"http://[2002:5db8:d822::1]/", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			u, _ := url.Parse(tt.url)
+			err := Policy{AllowPrivate: tt.private}.checkURL(u)
+			if blocked := errors.Is(err, ErrBlocked); blocked != tt.blocked {
+				t.Errorf("checkURL(%s) = %v, want blocked %v", tt.url, err, tt.blocked)
+			}
+		})
+	}
+}
@@ -1,76 +1,685 @@
 package limiter
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"net/url"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
+// ErrInvalidURL is returned for a URL that cannot be parsed or has no host.
+var ErrInvalidURL = errors.New("invalid URL")
+
 // FetchResult contains the result of a URL fetch operation.
 type FetchResult struct {
-	URL           string    `json:"url"`
-	HTML          string    `json:"html"`
-	StatusCode    int       `json:"status_code"`
-	ContentLength int64     `json:"content_length"`
-	FetchedAt     time.Time `json:"fetched_at"`
+	URL           string      `json:"url"`
+	FinalURL      string      `json:"final_url"`
+	Redirects     []Redirect  `json:"redirects,omitempty"`
+	HTML          string      `json:"html"`
+	BodyBase64    string      `json:"body_base64,omitempty"`
+	ContentType   string      `json:"content_type,omitempty"`
+	Charset       string      `json:"charset,omitempty"`
+	CharsetError  string      `json:"charset_error,omitempty"`
+	StatusCode    int         `json:"status_code"`
+	Headers       http.Header `json:"headers"`
+	ContentLength int64       `json:"content_length"`
+	Truncated     bool        `json:"truncated"`
+	FetchedAt     time.Time   `json:"fetched_at"`
+	WaitedMs      int64       `json:"waited_ms"`
+	Timings       Timings     `json:"timings"`
+	FromCache     bool        `json:"from_cache"`
+	NotModified   bool        `json:"not_modified"`
+	Shared        bool        `json:"shared"`
+}
+
+// Request describes a URL to fetch.
+type Request struct {
+	URL string
+	// Client identifies the caller for fair queuing and quotas, see
+	// clients.go. Empty means AnonymousClient.
+	Client string
+	// Method is the HTTP method to send. Empty means GET.
+	Method string
+	// Header holds extra request headers, such as Accept.
+	Header http.Header
+	// Body is sent as the request body, e.g. JSON for a POST.
+	Body     []byte
+	Priority Priority
+	// MaxAge lets a cached response fetched at most this long ago be served
+	// without a network request or a token. Zero always goes to the network.
+	// Only plain GET requests use the cache.
+	MaxAge time.Duration
+}
+
+// Recorder receives every request the rate limiter sends, with the response
+// and the body as read, e.g. to archive them. truncated is set if the body
+// was cut off at Policy.MaxBodySize.
+type Recorder interface {
+	Record(req *http.Request, resp *http.Response, body []byte, truncated bool, fetchedAt time.Time) error
+}
+
+// Policy modes reported by Policy.Mode.
+const (
+	ModeFixedInterval = "fixed-interval"
+	ModeTokenBucket   = "token-bucket"
+)
+
+// Policy describes how requests to each host are paced and served. Every
+// host gets a token bucket holding up to Burst tokens that earns one token
+// per interval.
+type Policy struct {
+	// DefaultInterval applies to every host without an entry in HostIntervals.
+	DefaultInterval time.Duration
+	// HostIntervals overrides the interval for specific hosts, keyed by
+	// lower-case host name without port.
+	HostIntervals map[string]time.Duration
+	// Burst is the number of requests a host may receive back to back after
+	// being idle. A burst of 0 or 1 means at most one request per interval.
+	Burst int
+	// ObeyRobots makes Fetch refuse paths disallowed by the host's robots.txt
+	// and treat its Crawl-delay as a minimum interval.
+	ObeyRobots bool
+	// RobotsTTL is how long a fetched robots.txt is cached.
+	RobotsTTL time.Duration
+	// CacheSize is the number of responses kept for conditional requests
+	// and max-age hits. Zero disables the cache.
+	CacheSize int
+	// CacheMaxBytes caps the total size of the cached bodies; larger bodies
+	// are not cached. Zero means no byte limit.
+	CacheMaxBytes int64
+	// AllowedSchemes lists the URL schemes that may be fetched. Empty means
+	// http and https.
+	AllowedSchemes []string
+	// AllowedHosts, if not empty, restricts fetches to these hosts and their
+	// subdomains.
+	AllowedHosts []string
+	// DeniedHosts are never fetched, nor are their subdomains.
+	DeniedHosts []string
+	// MaxBodySize is the number of body bytes read from a response, after
+	// decompression; the rest is dropped and the result marked truncated.
+	// Zero means no limit.
+	MaxBodySize int64
+	// AllowedContentTypes lists the media types a response may have, such as
+	// "text/html" or "text/*". Empty allows any type.
+	AllowedContentTypes []string
+	// AllowedMethods lists the HTTP methods that may be sent. Empty means
+	// GET, HEAD and POST.
+	AllowedMethods []string
+	// MaxRequestBodySize caps Request.Body. Zero means no limit.
+	MaxRequestBodySize int64
+	// AllowPrivate permits connections to loopback, private, link-local and
+	// other internal addresses, which are refused by default. See guard.go.
+	// It cannot be changed by UpdatePolicy.
+	AllowPrivate bool
+	// ClientWeights gives clients a larger (or smaller) share of each host's
+	// turns than the default weight of 1.
+	ClientWeights map[string]float64
+	// ClientQuotas limits the requests per UTC day of specific clients.
+	ClientQuotas map[string]int
+	// DefaultQuota limits the requests per UTC day of every other client.
+	// Zero means no limit.
+	DefaultQuota int
+	// BreakerThreshold is the number of consecutive network errors or 5xx
+	// responses after which a host's circuit opens. Zero disables the
+	// circuit breaker.
+	BreakerThreshold int
+	// BreakerCooldown is how long a circuit stays open before a probe
+	// request is let through.
+	BreakerCooldown time.Duration
 }
 
-// RateLimiter enforces a minimum interval between HTTP requests.
+// Mode returns ModeTokenBucket when bursts are allowed, ModeFixedInterval otherwise.
+func (p Policy) Mode() string {
+	if p.Burst > 1 {
+		return ModeTokenBucket
+	}
+	return ModeFixedInterval
+}
+
+// RateLimiter enforces a minimum interval between HTTP requests to the same
+// host. Each host has its own bucket, so requests to different hosts proceed
+// concurrently.
 type RateLimiter struct {
-	interval   time.Duration
-	lastFetch  time.Time
+	policy     Policy
+	policyMu   sync.RWMutex
+	buckets    map[string]*bucket
+	paused     bool
+	pausedAt   time.Time
 	mu         sync.Mutex
+	robots     map[string]*robotsEntry
+	robotsMu   sync.Mutex
+	cache      *responseCache
+	flights    *flightGroup
+	clients    *clients
+	metrics    *metrics
+	recorder   Recorder
 	httpClient *http.Client
+
+	// stateChanged is signalled by every bucket, see state.go.
+	stateChanged chan struct{}
+}
+
+// New creates a new RateLimiter with the specified policy.
+func New(policy Policy) *RateLimiter {
+	hostIntervals := make(map[string]time.Duration, len(policy.HostIntervals))
+	for host, interval := range policy.HostIntervals {
+		hostIntervals[strings.ToLower(host)] = interval
+	}
+	policy.HostIntervals = hostIntervals
+
+	r := &RateLimiter{
+		policy:  policy,
+		buckets: make(map[string]*bucket),
+		robots:  make(map[string]*robotsEntry),
+		cache:   newResponseCache(policy.CacheSize, policy.CacheMaxBytes),
+		flights: newFlightGroup(),
+		clients: newClients(),
+		metrics: newMetrics(),
+
+		stateChanged: make(chan struct{}, 1),
+	}
+	r.httpClient = &http.Client{
+		Transport:     r.newTransport(),
+		Timeout:       30 * time.Second,
+		CheckRedirect: r.checkRedirect,
+	}
+	return r
 }
 
-// New creates a new RateLimiter with the specified interval in seconds.
-func New(intervalSeconds int) *RateLimiter {
-	return &RateLimiter{
-		interval: time.Duration(intervalSeconds) * time.Second,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+// Policy returns a copy of the active policy.
+func (r *RateLimiter) Policy() Policy {
+	active := r.currentPolicy()
+	p := active
+	p.HostIntervals = make(map[string]time.Duration, len(active.HostIntervals))
+	for host, interval := range active.HostIntervals {
+		p.HostIntervals[host] = interval
+	}
+	p.AllowedSchemes = append([]string(nil), active.AllowedSchemes...)
+	p.AllowedHosts = append([]string(nil), active.AllowedHosts...)
+	p.DeniedHosts = append([]string(nil), active.DeniedHosts...)
+	p.AllowedContentTypes = append([]string(nil), active.AllowedContentTypes...)
+	p.AllowedMethods = append([]string(nil), active.AllowedMethods...)
+	p.ClientWeights = make(map[string]float64, len(active.ClientWeights))
+	for client, weight := range active.ClientWeights {
+		p.ClientWeights[client] = weight
+	}
+	p.ClientQuotas = make(map[string]int, len(active.ClientQuotas))
+	for client, quota := range active.ClientQuotas {
+		p.ClientQuotas[client] = quota
 	}
+	return p
+}
+
+// currentPolicy returns the active policy without copying it. UpdatePolicy
+// replaces its map rather than modifying it, so the result may be read freely
+// but must not be modified.
+func (r *RateLimiter) currentPolicy() Policy {
+	r.policyMu.RLock()
+	defer r.policyMu.RUnlock()
+	return r.policy
+}
+
+// SetRecorder makes every subsequent response, including robots.txt fetches,
+// redirects and 304s, go to rec. It must be called before the first Fetch.
+func (r *RateLimiter) SetRecorder(rec Recorder) {
+	r.recorder = rec
 }
 
-// Fetch retrieves the content from the specified URL, respecting the rate limit.
-// If a request was made recently, this method blocks until the rate limit allows.
-func (r *RateLimiter) Fetch(url string) (*FetchResult, error) {
+// SetTransport sends every subsequent request through rt instead of the
+// network, e.g. to replay an archive. It must be called before the first Fetch.
+func (r *RateLimiter) SetTransport(rt http.RoundTripper) {
+	r.httpClient.Transport = rt
+}
+
+// IntervalFor returns the minimum interval between requests to the given host.
+func (r *RateLimiter) IntervalFor(host string) time.Duration {
+	policy := r.currentPolicy()
+	if interval, ok := policy.HostIntervals[strings.ToLower(host)]; ok {
+		return interval
+	}
+	return policy.DefaultInterval
+}
+
+// bucketFor returns the bucket for the given host, creating it if needed.
+func (r *RateLimiter) bucketFor(host string) *bucket {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	// Wait if necessary to respect the rate limit
-	if !r.lastFetch.IsZero() {
-		elapsed := time.Since(r.lastFetch)
-		if elapsed < r.interval {
-			waitTime := r.interval - elapsed
-			time.Sleep(waitTime)
+	b, ok := r.buckets[host]
+	if !ok {
+		b = newBucket(host, r.IntervalFor(host), r.currentPolicy().Burst)
+		b.paused = r.paused
+		b.changed = r.stateChanged
+		b.clientWeight = r.clientWeight
+		b.breaker = newBreaker(r.currentPolicy().BreakerThreshold, r.currentPolicy().BreakerCooldown)
+		r.buckets[host] = b
+	}
+	return b
+}
+
+// Fetch retrieves the content from the specified URL, respecting the rate limit
+// for the URL's host. If the host's bucket has no token left, this method
+// blocks until one is earned. When the policy obeys robots.txt, a disallowed
+// URL fails with ErrDisallowed without using a token. A URL refused by the
+// policy's scheme, host or address rules fails with ErrBlocked.
+//
+// Requests waiting for the same host are served by priority, see queue.go.
+// If ctx is done while waiting, Fetch returns ctx.Err() without using a token;
+// if it is done during the request, the request is cancelled.
+//
+// A cached response younger than req.MaxAge is returned without touching the
+// network. Otherwise a cached response's ETag and Last-Modified are sent as
+// validators, and a 304 answer returns the cached body.
+//
+// Text bodies are returned as UTF-8 in HTML, other bodies base64-encoded in
+// BodyBase64, see content.go. A response whose content type the policy does
+// not allow fails with ErrContentType. The result also carries the response
+// headers, the redirect chain and a timing breakdown, see trace.go.
+//
+// Any method, headers and body the policy allows can be sent, see
+// request.go; other requests fail with ErrRequestNotAllowed. They share the
+// host's bucket with GETs.
+//
+// Concurrent calls for the same request share one upstream fetch and get the
+// same result, with Shared set, see flight.go.
+//
+// After repeated failures a host's circuit opens and requests to it fail at
+// once with ErrCircuitOpen, without waiting for a token, see breaker.go.
+//
+// Each host's turns are shared fairly between clients, and a client over its
+// daily quota fails with ErrQuotaExceeded, see clients.go. Only requests
+// that return a result count towards the quota; max-age hits are served
+// regardless and do not count.
+//
+// While the rate limiter is paused, requests wait in their host's queue
+// until it is resumed, see admin.go.
+func (r *RateLimiter) Fetch(ctx context.Context, req Request) (result *FetchResult, err error) {
+	defer func() { r.metrics.observeRequest(result, err) }()
+
+	rawURL := req.URL
+	u, host, err := r.parseTarget(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.currentPolicy().checkRequest(req); err != nil {
+		return nil, err
+	}
+
+	var cached cacheEntry
+	hasCached := false
+	if req.cacheable() {
+		cached, hasCached = r.cache.get(rawURL)
+	}
+	if hasCached && req.MaxAge > 0 && time.Since(cached.fetchedAt) <= req.MaxAge {
+		result = &FetchResult{
+			URL:        rawURL,
+			FinalURL:   cached.finalURL,
+			StatusCode: cached.statusCode,
+			Headers:    cached.header,
+			FetchedAt:  cached.fetchedAt,
+			FromCache:  true,
 		}
+		result.setBody(cached.body, cached.header.Get("Content-Type"))
+		return result, nil
 	}
 
-	// Record the fetch time
-	r.lastFetch = time.Now()
+	if err := r.bucketFor(host).checkCircuit(time.Now(), false); err != nil {
+		return nil, err
+	}
 
-	// Make the HTTP request
-	resp, err := r.httpClient.Get(url)
+	// Count the request now so concurrent ones cannot overshoot the quota,
+	// and take it back if the fetch fails: only results are charged
+	client := r.clientFor(req)
+	countedAt := time.Now()
+	if err := r.clients.check(client, r.currentPolicy().Quota(client), true, countedAt); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			r.clients.refund(client, countedAt)
+		}
+	}()
+
+	if !req.shareable() {
+		return r.fetch(ctx, req, u, host)
+	}
+	return r.flights.do(ctx, flightKey(req), func(ctx context.Context) (*FetchResult, error) {
+		return r.fetch(ctx, req, u, host)
+	})
+}
+
+// fetch waits for the host's turn and a token and sends the request,
+// revalidating the cached copy if there is one.
+func (r *RateLimiter) fetch(ctx context.Context, req Request, u *url.URL, host string) (*FetchResult, error) {
+	rawURL := req.URL
+
+	// Look again: a fetch that just finished may have refreshed the copy
+	var cached cacheEntry
+	hasCached := false
+	if req.cacheable() {
+		cached, hasCached = r.cache.get(rawURL)
+	}
+
+	b := r.bucketFor(host)
+	start := time.Now()
+	fetchedAt, probe, err := r.reserve(ctx, b, u, r.clientFor(req), req.Priority)
 	if err != nil {
+		return nil, err
+	}
+	if probe {
+		defer b.endProbe()
+	}
+	waited := fetchedAt.Sub(start)
+	r.metrics.observeWait(waited)
+
+	// Make the HTTP request, revalidating the cached copy if there is one
+	header := req.Header.Clone()
+	if hasCached {
+		header = cached.conditionalHeaders()
+	}
+	resp, err := r.get(ctx, b, req.method(), rawURL, req.Body, r.currentPolicy().MaxBodySize, header)
+	if err != nil {
+		return nil, err
+	}
+	_, configured := r.currentPolicy().HostIntervals[host]
+	r.metrics.observeFetch(host, configured, resp.StatusCode, time.Duration(resp.timings.TotalMs*float64(time.Millisecond)))
+
+	contentType := resp.Header.Get("Content-Type")
+	if resp.StatusCode != http.StatusNotModified && len(resp.body) > 0 {
+		if mt, _ := mediaType(contentType, resp.body); !contentTypeAllowed(r.currentPolicy().AllowedContentTypes, mt) {
+			return nil, fmt.Errorf("%w: %s is %s", ErrContentType, rawURL, mt)
+		}
+	}
+
+	resp.timings.WaitMs = ms(waited)
+	result := &FetchResult{
+		URL:        rawURL,
+		FinalURL:   resp.Request.URL.String(),
+		Redirects:  resp.redirects,
+		StatusCode: resp.StatusCode,
+		Headers:    resp.Header,
+		Truncated:  resp.truncated,
+		FetchedAt:  fetchedAt,
+		WaitedMs:   waited.Milliseconds(),
+		Timings:    resp.timings,
+	}
+	result.setBody(resp.body, contentType)
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && hasCached:
+		// The copy is fresh again; keep any updated headers and validators
+		cached.fetchedAt = fetchedAt
+		for key, values := range resp.Header {
+			if key != "Content-Length" {
+				cached.header[key] = values
+			}
+		}
+		r.cache.put(cached)
+
+		result.setBody(cached.body, cached.header.Get("Content-Type"))
+		result.StatusCode = cached.statusCode
+		result.Headers = cached.header
+		result.FromCache = true
+		result.NotModified = true
+	case resp.StatusCode == http.StatusOK && !resp.truncated && req.cacheable():
+		r.cache.put(cacheEntry{
+			url:        rawURL,
+			finalURL:   result.FinalURL,
+			body:       resp.body,
+			header:     resp.Header.Clone(),
+			statusCode: resp.StatusCode,
+			fetchedAt:  fetchedAt,
+		})
+	}
+
+	return result, nil
+}
+
+// reserve takes the bucket's turn, checks the circuit breaker and robots.txt
+// and waits for a token. The turn is released as soon as the token is
+// consumed, so the next request starts waiting while this one is in flight.
+// It reports whether the request is the circuit's probe, which must call
+// b.endProbe once it is done.
+func (r *RateLimiter) reserve(ctx context.Context, b *bucket, u *url.URL, client string, priority Priority) (fetchedAt time.Time, probe bool, err error) {
+	if err := b.acquire(ctx, u.String(), client, priority); err != nil {
+		return time.Time{}, false, err
+	}
+	defer b.release()
+
+	// Fail fast if the host is down, unless it is time to probe
+	probe, err = b.admitCircuit(time.Now())
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if probe {
+		defer func() {
+			if err != nil {
+				b.endProbe()
+			}
+		}()
+	}
+
+	// Check the host's robots.txt, fetching it first if needed
+	if r.currentPolicy().ObeyRobots {
+		rules, err := r.robotsFor(ctx, b, u)
+		if err != nil {
+			return time.Time{}, probe, err
+		}
+		if !rules.allowed(robotsPath(u)) {
+			return time.Time{}, probe, fmt.Errorf("%w: %s", ErrDisallowed, u)
+		}
+
+		// The robots.txt fetch may have found the host down
+		if err := b.checkCircuit(time.Now(), probe); err != nil {
+			return time.Time{}, probe, err
+		}
+	}
+
+	// Wait if necessary to respect the host's rate limit
+	fetchedAt, err = b.wait(ctx)
+	return fetchedAt, probe, err
+}
+
+// response is a response read by get.
+type response struct {
+	*http.Response
+	body      []byte
+	truncated bool
+	redirects []Redirect
+	timings   Timings
+}
+
+// get sends a request with the given method, body and extra headers to the
+// bucket's host and reads up to limit bytes of the decompressed body (all of
+// it if limit is 0), reporting whether more was dropped. The response status
+// feeds the host's backoff, and the status or a transport failure its
+// circuit breaker. The caller must have consumed a token.
+func (r *RateLimiter) get(ctx context.Context, b *bucket, method, rawURL string, body []byte, limit int64, header http.Header) (*response, error) {
+	start := time.Now()
+	ctx, trace := withTrace(ctx)
+	var reqBody io.Reader
+	if len(body) > 0 {
+		reqBody = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch URL: %w", err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		// Report a refused address or redirect as such, not as a network error
+		var blocked *blockedError
+		if errors.As(err, &blocked) {
+			var urlErr *url.Error
+			if blocked.url == "" && errors.As(err, &urlErr) {
+				blocked.url = urlErr.URL
+			}
+			return nil, blocked
+		}
+		// Neither a client that gave up nor a request the transport refused,
+		// e.g. for an invalid header, says anything about the host
+		if ctx.Err() == nil && hostFailure(err) {
+			b.observeOutcome(0, err, time.Now())
+		}
 		return nil, fmt.Errorf("failed to fetch URL: %w", err)
 	}
 	defer resp.Body.Close()
 
-	// Read the response body
-	body, err := io.ReadAll(resp.Body)
+	// Slow down if the host is pushing back, recover otherwise
+	b.observe(resp.StatusCode, resp.Header, time.Now())
+	b.observeOutcome(resp.StatusCode, nil, time.Now())
+
+	// Read the response body, decompressed, plus one byte to detect truncation
+	if err := decodeContent(resp); err != nil {
+		return nil, err
+	}
+	var reader io.Reader = resp.Body
+	if limit > 0 {
+		reader = io.LimitReader(resp.Body, limit+1)
+	}
+	respBody, err := io.ReadAll(reader)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
+	truncated := limit > 0 && int64(len(respBody)) > limit
+	if truncated {
+		respBody = respBody[:limit]
+	}
+
+	// resp.Request is the last hop if the request was redirected
+	r.record(resp.Request, resp, respBody, truncated)
 
-	return &FetchResult{
-		URL:           url,
-		HTML:          string(body),
-		StatusCode:    resp.StatusCode,
-		ContentLength: int64(len(body)),
-		FetchedAt:     r.lastFetch,
+	redirects, timings := trace.result()
+	timings.TotalMs = ms(time.Since(start))
+	return &response{
+		Response:  resp,
+		body:      respBody,
+		truncated: truncated,
+		redirects: redirects,
+		timings:   timings,
 	}, nil
 }
+
+// checkRedirect archives each redirect response before it is followed,
+// since the client discards it, applies the policy's URL rules to the
+// target and stops after 10 redirects like the default policy.
+func (r *RateLimiter) checkRedirect(req *http.Request, via []*http.Request) error {
+	if req.Response != nil {
+		r.record(via[len(via)-1], req.Response, nil, false)
+		if trace := traceFrom(req); trace != nil {
+			trace.addRedirect(Redirect{
+				URL:        via[len(via)-1].URL.String(),
+				StatusCode: req.Response.StatusCode,
+				Location:   req.URL.String(),
+			})
+		}
+	}
+	if err := r.currentPolicy().checkURL(req.URL); err != nil {
+		return err
+	}
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	return nil
+}
+
+// record hands an exchange to the recorder, if any. Archiving is best effort
+// and never fails the fetch.
+func (r *RateLimiter) record(req *http.Request, resp *http.Response, body []byte, truncated bool) {
+	if r.recorder == nil {
+		return
+	}
+	if err := r.recorder.Record(req, resp, body, truncated, time.Now()); err != nil {
+		log.Printf("Failed to archive %s: %v", req.URL, err)
+	}
+}
+
+// Hosts returns the rate limit state of every host seen so far, sorted by host.
+func (r *RateLimiter) Hosts() []HostStatus {
+	buckets := r.sortedBuckets()
+	hosts := make([]HostStatus, len(buckets))
+	for i, b := range buckets {
+		hosts[i] = b.status()
+	}
+	return hosts
+}
+
+// EstimateStart returns when the request would be sent if it were submitted
+// now. It does not account for a robots.txt fetch the request may trigger.
+// Like Fetch, it fails with ErrBlocked or ErrRequestNotAllowed for a request
+// the policy refuses, with ErrCircuitOpen while the host's circuit is open,
+// and with ErrQuotaExceeded for a client without quota left, without
+// counting the request.
+func (r *RateLimiter) EstimateStart(req Request) (time.Time, error) {
+	_, host, err := r.parseTarget(req.URL)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if err := r.currentPolicy().checkRequest(req); err != nil {
+		return time.Time{}, err
+	}
+	if err := r.bucketFor(host).checkCircuit(time.Now(), false); err != nil {
+		return time.Time{}, err
+	}
+	client := r.clientFor(req)
+	if err := r.clients.check(client, r.currentPolicy().Quota(client), false, time.Now()); err != nil {
+		return time.Time{}, err
+	}
+	return r.bucketFor(host).estimateStart(client, req.Priority), nil
+}
+
+// Queue returns the pending requests of every host with a non-empty queue,
+// sorted by host.
+func (r *RateLimiter) Queue() []QueueStatus {
+	queues := []QueueStatus{}
+	for _, b := range r.sortedBuckets() {
+		if q := b.queueStatus(); len(q.Entries) > 0 {
+			queues = append(queues, q)
+		}
+	}
+	return queues
+}
+
+// sortedBuckets returns every bucket, sorted by host.
+func (r *RateLimiter) sortedBuckets() []*bucket {
+	r.mu.Lock()
+	buckets := make([]*bucket, 0, len(r.buckets))
+	for _, b := range r.buckets {
+		buckets = append(buckets, b)
+	}
+	r.mu.Unlock()
+
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].host < buckets[j].host })
+	return buckets
+}
+
+// parseTarget parses the URL, applies the policy's URL rules and returns it
+// with its lower-case host name (without port).
+func (r *RateLimiter) parseTarget(rawURL string) (*url.URL, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
+		return nil, "", fmt.Errorf("%w: missing host", ErrInvalidURL)
+	}
+	if err := r.currentPolicy().checkURL(u); err != nil {
+		return nil, "", err
+	}
+	return u, host, nil
+}
@@ -0,0 +1,287 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// recentSamples is how many of the latest observations percentiles are
+// computed over, so they describe current behaviour rather than all time.
+const recentSamples = 1000
+
+// maxFetchHosts is how many hosts without their own interval are counted by
+// name in Stats.Fetches. Callers choose the hosts, so later ones are counted
+// as OtherHosts to keep the set of metric labels bounded.
+const maxFetchHosts = 100
+
+// OtherHosts is the Stats.Fetches key of the hosts beyond maxFetchHosts.
+const OtherHosts = "other"
+
+// Histogram bucket upper bounds, in seconds. Waits range from nothing to
+// several Crawl-delays; upstream latencies are usually well under a second.
+var (
+	waitBounds    = []float64{0.01, 0.05, 0.1, 0.5, 1, 2, 5, 10, 30, 60, 120, 300}
+	latencyBounds = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+)
+
+// Error kinds counted in Stats.Errors.
+const (
+	ErrorKindDisallowed        = "robots_disallowed"
+	ErrorKindBlocked           = "url_blocked"
+	ErrorKindRequestNotAllowed = "request_not_allowed"
+	ErrorKindContentType       = "content_type_not_allowed"
+	ErrorKindQuotaExceeded     = "quota_exceeded"
+	ErrorKindCircuitOpen       = "circuit_open"
+	ErrorKindCanceled          = "canceled"
+	ErrorKindFetchFailed       = "fetch_failed"
+)
+
+// Stats is a point-in-time copy of the rate limiter's counters.
+type Stats struct {
+	StartedAt time.Time
+	// Requests counts Fetch calls, CacheHits those answered from the cache
+	// (after a max-age hit or a 304) and Shared those answered by a fetch
+	// shared with identical requests.
+	Requests  int64
+	CacheHits int64
+	Shared    int64
+	// Fetches counts upstream responses by host and status class ("2xx").
+	// Hosts with their own interval are always listed, the first
+	// maxFetchHosts others too, and the rest under OtherHosts.
+	Fetches map[string]map[string]int64
+	// Errors counts failed Fetch calls by kind, see the ErrorKind constants.
+	Errors map[string]int64
+	// Wait is the time spent waiting for the host's turn, robots.txt and a
+	// token; Latency the time from sending the request to reading the body.
+	// Both are in seconds.
+	Wait    Distribution
+	Latency Distribution
+}
+
+// Distribution summarizes observed durations in seconds.
+type Distribution struct {
+	Count int64
+	Sum   float64
+	// Buckets are cumulative, as in a Prometheus histogram, and do not
+	// include the implicit +Inf bucket, whose count is Count.
+	Buckets []HistogramBucket
+	// Percentiles of the most recent observations, zero if there are none.
+	P50 float64
+	P90 float64
+	P99 float64
+}
+
+// HistogramBucket is the number of observations less than or equal to
+// UpperBound.
+type HistogramBucket struct {
+	UpperBound float64
+	Count      int64
+}
+
+// metrics collects the rate limiter's counters.
+type metrics struct {
+	startedAt time.Time
+	requests  int64
+	cacheHits int64
+	shared    int64
+	fetches   map[string]map[string]int64
+	errors    map[string]int64
+	wait      *distribution
+	latency   *distribution
+	mu        sync.Mutex
+
+	// unconfigured counts the hosts in fetches without their own interval.
+	unconfigured int
+}
+
+// distribution is a histogram plus a ring of recent observations.
+type distribution struct {
+	bounds []float64
+	counts []int64
+	count  int64
+	sum    float64
+	recent []float64
+	next   int
+}
+
+// newMetrics creates empty metrics starting now.
+func newMetrics() *metrics {
+	return &metrics{
+		startedAt: time.Now(),
+		fetches:   make(map[string]map[string]int64),
+		errors:    make(map[string]int64),
+		wait:      newDistribution(waitBounds),
+		latency:   newDistribution(latencyBounds),
+	}
+}
+
+// newDistribution creates an empty distribution with the given bucket bounds.
+func newDistribution(bounds []float64) *distribution {
+	return &distribution{
+		bounds: bounds,
+		counts: make([]int64, len(bounds)),
+		recent: make([]float64, 0, recentSamples),
+	}
+}
+
+// observeRequest counts the outcome of a Fetch call.
+func (m *metrics) observeRequest(result *FetchResult, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests++
+	if err != nil {
+		m.errors[errorKind(err)]++
+		return
+	}
+	if result.FromCache {
+		m.cacheHits++
+	}
+	if result.Shared {
+		m.shared++
+	}
+}
+
+// observeWait records how long a fetch waited for its token.
+func (m *metrics) observeWait(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.wait.observe(d.Seconds())
+}
+
+// observeFetch counts an upstream response and records its latency.
+// configured is set for hosts with their own interval, which are always
+// counted by name.
+func (m *metrics) observeFetch(host string, configured bool, statusCode int, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	classes, ok := m.fetches[host]
+	if !ok && !configured {
+		if m.unconfigured >= maxFetchHosts {
+			host = OtherHosts
+			classes, ok = m.fetches[host]
+		} else {
+			m.unconfigured++
+		}
+	}
+	if !ok {
+		classes = make(map[string]int64)
+		m.fetches[host] = classes
+	}
+	classes[fmt.Sprintf("%dxx", statusCode/100)]++
+	m.latency.observe(latency.Seconds())
+}
+
+// snapshot returns a copy of the counters.
+func (m *metrics) snapshot() Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := Stats{
+		StartedAt: m.startedAt,
+		Requests:  m.requests,
+		CacheHits: m.cacheHits,
+		Shared:    m.shared,
+		Fetches:   make(map[string]map[string]int64, len(m.fetches)),
+		Errors:    make(map[string]int64, len(m.errors)),
+		Wait:      m.wait.snapshot(),
+		Latency:   m.latency.snapshot(),
+	}
+	for host, classes := range m.fetches {
+		s.Fetches[host] = make(map[string]int64, len(classes))
+		for class, n := range classes {
+			s.Fetches[host][class] = n
+		}
+	}
+	for kind, n := range m.errors {
+		s.Errors[kind] = n
+	}
+	return s
+}
+
+// observe adds a value to the histogram and the recent observations.
+func (d *distribution) observe(v float64) {
+	for i, bound := range d.bounds {
+		if v <= bound {
+			d.counts[i]++
+			break
+		}
+	}
+	d.count++
+	d.sum += v
+
+	if len(d.recent) < cap(d.recent) {
+		d.recent = append(d.recent, v)
+	} else {
+		d.recent[d.next] = v
+	}
+	d.next = (d.next + 1) % cap(d.recent)
+}
+
+// snapshot returns the distribution with cumulative buckets and percentiles.
+func (d *distribution) snapshot() Distribution {
+	s := Distribution{
+		Count:   d.count,
+		Sum:     d.sum,
+		Buckets: make([]HistogramBucket, len(d.bounds)),
+	}
+	var cumulative int64
+	for i, bound := range d.bounds {
+		cumulative += d.counts[i]
+		s.Buckets[i] = HistogramBucket{UpperBound: bound, Count: cumulative}
+	}
+
+	if len(d.recent) > 0 {
+		sorted := append([]float64(nil), d.recent...)
+		sort.Float64s(sorted)
+		s.P50 = percentile(sorted, 0.50)
+		s.P90 = percentile(sorted, 0.90)
+		s.P99 = percentile(sorted, 0.99)
+	}
+	return s
+}
+
+// percentile returns the nearest-rank percentile p of sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
+
+// errorKind classifies a Fetch error for Stats.Errors.
+func errorKind(err error) string {
+	switch {
+	case errors.Is(err, ErrDisallowed):
+		return ErrorKindDisallowed
+	case errors.Is(err, ErrRequestNotAllowed):
+		return ErrorKindRequestNotAllowed
+	case errors.Is(err, ErrBlocked):
+		return ErrorKindBlocked
+	case errors.Is(err, ErrContentType):
+		return ErrorKindContentType
+	case errors.Is(err, ErrQuotaExceeded):
+		return ErrorKindQuotaExceeded
+	case errors.Is(err, ErrCircuitOpen):
+		return ErrorKindCircuitOpen
+	case errors.Is(err, context.Canceled):
+		return ErrorKindCanceled
+	default:
+		return ErrorKindFetchFailed
+	}
+}
+
+// Stats returns the rate limiter's counters since it was created.
+func (r *RateLimiter) Stats() Stats {
+	return r.metrics.snapshot()
+}
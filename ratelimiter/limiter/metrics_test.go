@@ -0,0 +1,41 @@
+package limiter
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestFetchHostsAreBounded(t *testing.T) {
+	m := newMetrics()
+	for i := 0; i < maxFetchHosts+50; i++ {
+		m.observeFetch(fmt.Sprintf("host%d.example", i), false, 200, time.Millisecond)
+	}
+	// A host seen before the cap keeps its own counts, and a configured
+	// host gets them even after it
+	m.observeFetch("host0.example", false, 500, time.Millisecond)
+	m.observeFetch("news.ycombinator.com", true, 200, time.Millisecond)
+
+	stats := m.snapshot()
+	if got, want := len(stats.Fetches), maxFetchHosts+2; got != want {
+		t.Errorf("%d hosts in fetches, want %d", got, want)
+	}
+
+	tests := []struct {
+		host  string
+		class string
+		want  int64
+	}{
+		{"host0.example", "2xx", 1},
+		{"host0.example", "5xx", 1},
+		{fmt.Sprintf("host%d.example", maxFetchHosts-1), "2xx", 1},
+		{fmt.Sprintf("host%d.example", maxFetchHosts), "2xx", 0},
+		{OtherHosts, "2xx", 50},
+		{"news.ycombinator.com", "2xx", 1},
+	}
+	for _, tt := range tests {
+		if got := stats.Fetches[tt.host][tt.class]; got != tt.want {
+			t.Errorf("fetches[%s][%s] = %d, want %d", tt.host, tt.class, got, tt.want)
+		}
+	}
+}
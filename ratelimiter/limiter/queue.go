@@ -0,0 +1,286 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Priority orders requests waiting for the same host.
+type Priority string
+
+// Request priorities, from most to least urgent.
+const (
+	PriorityInteractive Priority = "interactive"
+	PriorityScheduled   Priority = "scheduled"
+	PriorityBulk        Priority = "bulk"
+)
+
+// agingStep is how long a request must wait to be promoted one priority
+// level, so low priorities are never starved.
+const agingStep = 30 * time.Second
+
+// ParsePriority parses a priority name. An empty name means PriorityInteractive.
+func ParsePriority(s string) (Priority, error) {
+	switch p := Priority(s); p {
+	case "":
+		return PriorityInteractive, nil
+	case PriorityInteractive, PriorityScheduled, PriorityBulk:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown priority %q (expected %s, %s or %s)", s, PriorityInteractive, PriorityScheduled, PriorityBulk)
+	}
+}
+
+// rank returns 0 for the most urgent priority.
+func (p Priority) rank() int {
+	switch p {
+	case PriorityScheduled:
+		return 1
+	case PriorityBulk:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// waiter is a request queued for a bucket's turn.
+type waiter struct {
+	url        string
+	client     string
+	priority   Priority
+	enqueuedAt time.Time
+	ready      chan struct{}
+}
+
+// effectiveRank is the waiter's priority rank, lowered by one for every
+// agingStep it has waited.
+func (w *waiter) effectiveRank(now time.Time) int {
+	return w.priority.rank() - int(now.Sub(w.enqueuedAt)/agingStep)
+}
+
+// QueueEntry is a request waiting for, or holding, a host's turn.
+type QueueEntry struct {
+	URL            string    `json:"url"`
+	Client         string    `json:"client"`
+	Priority       Priority  `json:"priority"`
+	EnqueuedAt     time.Time `json:"enqueued_at"`
+	Active         bool      `json:"active"`
+	EstimatedStart time.Time `json:"estimated_start"`
+}
+
+// QueueStatus lists the requests pending for one host in the order they
+// are expected to be sent.
+type QueueStatus struct {
+	Host    string       `json:"host"`
+	Entries []QueueEntry `json:"entries"`
+}
+
+// acquire queues the caller for the bucket's turn and blocks until it is
+// granted or ctx is done. A cancelled caller leaves the queue. The turn
+// entitles the holder to check robots.txt and take the next token.
+func (b *bucket) acquire(ctx context.Context, url, client string, priority Priority) error {
+	w := &waiter{
+		url:        url,
+		client:     client,
+		priority:   priority,
+		enqueuedAt: time.Now(),
+		ready:      make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	b.queue = append(b.queue, w)
+	b.dispatch()
+	b.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.holder == w {
+		// Granted while giving up: pass the turn on
+		b.holder = nil
+		b.dispatch()
+	} else {
+		b.remove(w)
+	}
+	return ctx.Err()
+}
+
+// release gives up the bucket's turn and grants it to the next waiter.
+func (b *bucket) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.holder = nil
+	b.dispatch()
+}
+
+// dispatch grants the turn to the next waiter, see pick, once it is free
+// and a token is available, so waiters are compared when the request can
+// actually be sent. Nothing is granted while the bucket is paused. The
+// caller must hold b.mu.
+func (b *bucket) dispatch() {
+	if b.holder != nil || len(b.queue) == 0 || b.paused {
+		return
+	}
+
+	now := time.Now()
+	if d := b.delay(now); d > 0 {
+		if b.timer == nil {
+			b.timer = time.AfterFunc(d, func() {
+				b.mu.Lock()
+				defer b.mu.Unlock()
+				b.timer = nil
+				b.dispatch()
+			})
+		}
+		return
+	}
+
+	i := pick(b.queue, b.fair, now)
+	b.holder = b.queue[i]
+	b.queue = append(b.queue[:i], b.queue[i+1:]...)
+	b.fair.charge(b.holder.client, b.clientWeight(b.holder.client))
+	close(b.holder.ready)
+}
+
+// pick returns the index of the waiter to serve next: the one whose client
+// is furthest behind its fair share, then the most urgent of that client's
+// requests by effective rank, then the earliest. Without client IDs every
+// request belongs to the same client, so only priority and arrival count.
+func pick(queue []*waiter, fair *fairShare, now time.Time) int {
+	best := 0
+	for i := 1; i < len(queue); i++ {
+		a, b := queue[i], queue[best]
+		if ta, tb := fair.tag(a.client), fair.tag(b.client); ta != tb {
+			if ta < tb {
+				best = i
+			}
+			continue
+		}
+		if ra, rb := a.effectiveRank(now), b.effectiveRank(now); ra != rb {
+			if ra < rb {
+				best = i
+			}
+			continue
+		}
+		if a.enqueuedAt.Before(b.enqueuedAt) {
+			best = i
+		}
+	}
+	return best
+}
+
+// order returns the queue in the order it is expected to be served, by
+// repeating pick on a copy of the fair share state. The caller must hold b.mu.
+func (b *bucket) order(queue []*waiter, now time.Time) []*waiter {
+	remaining := append([]*waiter(nil), queue...)
+	fair := b.fair.clone()
+	ordered := make([]*waiter, 0, len(remaining))
+	for len(remaining) > 0 {
+		i := pick(remaining, fair, now)
+		w := remaining[i]
+		remaining = append(remaining[:i], remaining[i+1:]...)
+		fair.charge(w.client, b.clientWeight(w.client))
+		ordered = append(ordered, w)
+	}
+	return ordered
+}
+
+// remove drops w from the queue. The caller must hold b.mu.
+func (b *bucket) remove(w *waiter) {
+	for i, q := range b.queue {
+		if q == w {
+			b.queue = append(b.queue[:i], b.queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// queueStatus returns the bucket's pending requests with estimated start
+// times.
+func (b *bucket) queueStatus() QueueStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	pending := b.pending(now)
+	starts := b.schedule(len(pending), now)
+
+	entries := make([]QueueEntry, len(pending))
+	for i, w := range pending {
+		entries[i] = QueueEntry{
+			URL:            w.url,
+			Client:         w.client,
+			Priority:       w.priority,
+			EnqueuedAt:     w.enqueuedAt,
+			Active:         w == b.holder,
+			EstimatedStart: starts[i],
+		}
+	}
+
+	return QueueStatus{Host: b.host, Entries: entries}
+}
+
+// estimateStart returns when a request from the client with the given
+// priority would be sent if it joined the queue now.
+func (b *bucket) estimateStart(client string, priority Priority) time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	w := &waiter{client: client, priority: priority, enqueuedAt: now}
+	ordered := b.order(append(append([]*waiter(nil), b.queue...), w), now)
+
+	position := 0
+	if b.holder != nil {
+		position = 1
+	}
+	for _, q := range ordered {
+		if q == w {
+			break
+		}
+		position++
+	}
+
+	starts := b.schedule(position+1, now)
+	return starts[position]
+}
+
+// pending returns the turn holder, if any, followed by the queue in the
+// order it will be served. The caller must hold b.mu.
+func (b *bucket) pending(now time.Time) []*waiter {
+	ordered := b.order(b.queue, now)
+	if b.holder == nil {
+		return ordered
+	}
+	return append([]*waiter{b.holder}, ordered...)
+}
+
+// schedule estimates the start times of the next n requests, assuming each
+// one takes the next token as it is earned. The caller must hold b.mu.
+func (b *bucket) schedule(n int, now time.Time) []time.Time {
+	b.refill(now)
+
+	at := now
+	if b.blockedUntil.After(at) {
+		at = b.blockedUntil
+	}
+	tokens := b.tokens
+	interval := b.effectiveInterval()
+
+	starts := make([]time.Time, n)
+	for i := range starts {
+		if tokens < 1 {
+			at = at.Add(time.Duration((1 - tokens) * float64(interval)))
+			tokens = 1
+		}
+		tokens--
+		starts[i] = at
+	}
+	return starts
+}
@@ -0,0 +1,127 @@
+package limiter
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrRequestNotAllowed is returned for a request whose method, headers or
+// body the policy refuses.
+var ErrRequestNotAllowed = errors.New("request not allowed")
+
+// defaultMethods are allowed when Policy.AllowedMethods is empty.
+var defaultMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+
+// forbiddenHeaders may not be set by callers: they describe the connection
+// or the body, or are managed by the rate limiter itself (User-Agent for
+// robots.txt, Accept-Encoding for decompression, validators for the cache).
+var forbiddenHeaders = map[string]bool{
+	"Host":                true,
+	"Content-Length":      true,
+	"Transfer-Encoding":   true,
+	"Connection":          true,
+	"Keep-Alive":          true,
+	"Upgrade":             true,
+	"Te":                  true,
+	"Trailer":             true,
+	"Proxy-Authorization": true,
+	"Proxy-Connection":    true,
+	"User-Agent":          true,
+	"Accept-Encoding":     true,
+	"If-None-Match":       true,
+	"If-Modified-Since":   true,
+}
+
+// method returns the request's method, GET if none is set.
+func (req Request) method() string {
+	if req.Method == "" {
+		return http.MethodGet
+	}
+	return strings.ToUpper(req.Method)
+}
+
+// cacheable reports whether the request may be answered from, and stored
+// in, the response cache: a plain GET, since the cache is keyed by URL only.
+func (req Request) cacheable() bool {
+	return req.method() == http.MethodGet && len(req.Header) == 0
+}
+
+// shareable reports whether identical concurrent requests may share one
+// upstream fetch: only GET and HEAD, which are safe to send once for every
+// caller. A POST or DELETE from two callers is meant to happen twice.
+func (req Request) shareable() bool {
+	method := req.method()
+	return method == http.MethodGet || method == http.MethodHead
+}
+
+// Methods returns the HTTP methods that may be sent.
+func (p Policy) Methods() []string {
+	if len(p.AllowedMethods) == 0 {
+		return append([]string(nil), defaultMethods...)
+	}
+	return append([]string(nil), p.AllowedMethods...)
+}
+
+// checkRequest applies the policy's method, header and body rules.
+func (p Policy) checkRequest(req Request) error {
+	method := req.method()
+	methods := p.Methods()
+	if !containsFold(methods, method) {
+		return fmt.Errorf("%w: method %s is not allowed (allowed: %s)", ErrRequestNotAllowed, method, strings.Join(methods, ", "))
+	}
+
+	for key, values := range req.Header {
+		if !validHeaderName(key) {
+			return fmt.Errorf("%w: invalid header name %q", ErrRequestNotAllowed, key)
+		}
+		for _, value := range values {
+			if !validHeaderValue(value) {
+				return fmt.Errorf("%w: invalid value for header %s", ErrRequestNotAllowed, http.CanonicalHeaderKey(key))
+			}
+		}
+		if forbiddenHeaders[http.CanonicalHeaderKey(key)] || strings.HasPrefix(http.CanonicalHeaderKey(key), "Proxy-") {
+			return fmt.Errorf("%w: header %s may not be set", ErrRequestNotAllowed, http.CanonicalHeaderKey(key))
+		}
+	}
+
+	if len(req.Body) > 0 {
+		if method == http.MethodGet || method == http.MethodHead {
+			return fmt.Errorf("%w: %s requests may not have a body", ErrRequestNotAllowed, method)
+		}
+		if p.MaxRequestBodySize > 0 && int64(len(req.Body)) > p.MaxRequestBodySize {
+			return fmt.Errorf("%w: body is %d bytes, the limit is %d", ErrRequestNotAllowed, len(req.Body), p.MaxRequestBodySize)
+		}
+	}
+	return nil
+}
+
+// validHeaderName reports whether name is an RFC 9110 token, as the
+// transport requires of header names.
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// validHeaderValue reports whether value may be sent as a header value: no
+// control characters other than tab, so no CR, LF or NUL.
+func validHeaderValue(value string) bool {
+	for i := 0; i < len(value); i++ {
+		if c := value[i]; (c < ' ' && c != '\t') || c == 0x7f {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,44 @@
+package limiter
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestCheckRequest(t *testing.T) {
+	policy := Policy{MaxRequestBodySize: 16}
+	tests := []struct {
+		name string
+		req  Request
+		ok   bool
+	}{
+		{"plain get", Request{}, true},
+		{"post with body", Request{Method: "post", Body: []byte(`{"q":1}`)}, true},
+		{"accept header", Request{Header: http.Header{"Accept": {"text/html"}}}, true},
+		{"value with tab", Request{Header: http.Header{"X-Note": {"a\tb"}}}, true},
+		{"method not allowed", Request{Method: http.MethodDelete}, false},
+		{"body on get", Request{Body: []byte("x")}, false},
+		{"body too large", Request{Method: http.MethodPost, Body: make([]byte, 17)}, false},
+		{"forbidden header", Request{Header: http.Header{"Host": {"example.org"}}}, false},
+		{"proxy header", Request{Header: http.Header{"Proxy-Foo": {"1"}}}, false},
+		{"name with space", Request{Header: http.Header{"X Note": {"1"}}}, false},
+		{"name with colon", Request{Header: http.Header{"X-Note:": {"1"}}}, false},
+		{"empty name", Request{Header: http.Header{"": {"1"}}}, false},
+		{"value with CRLF", Request{Header: http.Header{"X-Note": {"a\r\nX-Injected: 1"}}}, false},
+		{"value with LF", Request{Header: http.Header{"X-Note": {"a\nb"}}}, false},
+		{"value with NUL", Request{Header: http.Header{"X-Note": {"a\x00b"}}}, false},
+		{"second value invalid", Request{Header: http.Header{"X-Note": {"ok", "bad\r"}}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.checkRequest(tt.req)
+			if tt.ok && err != nil {
+				t.Errorf("checkRequest() = %v, want nil", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrRequestNotAllowed) {
+				t.Errorf("checkRequest() = %v, want ErrRequestNotAllowed", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package limiter
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UserAgent is sent with every request and selects the robots.txt group the
+// rate limiter obeys.
+const UserAgent = "superpage-ratelimiter/1.0"
+
+const (
+	// robotsAgent is the product token matched against User-agent lines.
+	robotsAgent = "superpage-ratelimiter"
+	// maxRobotsSize is the most of a robots.txt file that is parsed.
+	maxRobotsSize = 512 * 1024
+	// robotsRetryTTL is how long a failed robots.txt fetch is cached before
+	// it is retried, if shorter than the configured TTL.
+	robotsRetryTTL = 5 * time.Minute
+)
+
+// ErrDisallowed is returned by Fetch when the host's robots.txt disallows the path.
+var ErrDisallowed = errors.New("disallowed by robots.txt")
+
+// robotsRule is a single Allow or Disallow line.
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// robotsRules are the rules from a robots.txt that apply to this rate limiter.
+type robotsRules struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// robotsEntry is a cached robots.txt for one host.
+type robotsEntry struct {
+	url        string
+	rules      *robotsRules
+	statusCode int
+	err        string
+	fetchedAt  time.Time
+	expiresAt  time.Time
+}
+
+// RobotsStatus is a view of a cached robots.txt for the admin API.
+type RobotsStatus struct {
+	Host              string    `json:"host"`
+	URL               string    `json:"url"`
+	StatusCode        int       `json:"status_code,omitempty"`
+	Error             string    `json:"error,omitempty"`
+	CrawlDelaySeconds float64   `json:"crawl_delay_seconds"`
+	Allow             []string  `json:"allow"`
+	Disallow          []string  `json:"disallow"`
+	FetchedAt         time.Time `json:"fetched_at"`
+	ExpiresAt         time.Time `json:"expires_at"`
+	Expired           bool      `json:"expired"`
+}
+
+// parseRobots parses a robots.txt body and keeps the groups that apply to
+// agent: every group naming it, or the "*" groups if none do.
+func parseRobots(body, agent string) *robotsRules {
+	agent = strings.ToLower(agent)
+
+	var specific, wildcard robotsRules
+	var matchesAgent, matchesWildcard, inRules bool
+
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// A user-agent line after rules starts a new group
+			if inRules {
+				matchesAgent, matchesWildcard, inRules = false, false, false
+			}
+			token := strings.ToLower(value)
+			if token == "*" {
+				matchesWildcard = true
+			} else if token != "" && strings.Contains(agent, token) {
+				matchesAgent = true
+			}
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				continue
+			}
+			rule := robotsRule{allow: key == "allow", pattern: value}
+			if matchesAgent {
+				specific.rules = append(specific.rules, rule)
+			}
+			if matchesWildcard {
+				wildcard.rules = append(wildcard.rules, rule)
+			}
+		case "crawl-delay":
+			inRules = true
+			secs, err := strconv.ParseFloat(value, 64)
+			if err != nil || secs < 0 {
+				continue
+			}
+			delay := time.Duration(secs * float64(time.Second))
+			if matchesAgent && delay > specific.crawlDelay {
+				specific.crawlDelay = delay
+			}
+			if matchesWildcard && delay > wildcard.crawlDelay {
+				wildcard.crawlDelay = delay
+			}
+		}
+	}
+
+	if len(specific.rules) > 0 || specific.crawlDelay > 0 {
+		return &specific
+	}
+	return &wildcard
+}
+
+// allowed reports whether the path (including any query string) may be
+// fetched. The longest matching rule wins; Allow wins a tie.
+func (rr *robotsRules) allowed(path string) bool {
+	if path == "/robots.txt" {
+		return true
+	}
+
+	best := -1
+	allow := true
+	for _, rule := range rr.rules {
+		if !robotsMatch(rule.pattern, path) {
+			continue
+		}
+		if len(rule.pattern) > best || (len(rule.pattern) == best && rule.allow) {
+			best = len(rule.pattern)
+			allow = rule.allow
+		}
+	}
+	return allow
+}
+
+// robotsMatch matches a robots.txt path pattern, supporting the "*" wildcard
+// and a trailing "$" end anchor.
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	if len(parts) == 1 {
+		return !anchored || rest == ""
+	}
+
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(part):]
+	}
+
+	last := parts[len(parts)-1]
+	if anchored {
+		return strings.HasSuffix(rest, last)
+	}
+	return strings.Contains(rest, last)
+}
+
+// robotsPath returns the part of the URL matched against robots.txt rules.
+func robotsPath(u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return path
+}
+
+// robotsFor returns the robots.txt rules for the URL's host, fetching them
+// through the host's bucket if the cached copy is missing or expired. A
+// robots.txt that cannot be fetched, or answers with a status other than
+// 200, allows everything. An error is returned only if ctx is done. The
+// caller must hold the bucket's turn.
+func (r *RateLimiter) robotsFor(ctx context.Context, b *bucket, u *url.URL) (*robotsRules, error) {
+	now := time.Now()
+
+	r.robotsMu.Lock()
+	entry, ok := r.robots[b.host]
+	r.robotsMu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.rules, nil
+	}
+
+	robotsURL := (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/robots.txt"}).String()
+	entry = &robotsEntry{
+		url:   robotsURL,
+		rules: &robotsRules{},
+	}
+
+	if _, err := b.wait(ctx); err != nil {
+		return nil, err
+	}
+	resp, err := r.get(ctx, b, http.MethodGet, robotsURL, nil, maxRobotsSize, nil)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	entry.fetchedAt = time.Now()
+	ttl := r.currentPolicy().RobotsTTL
+	switch {
+	case err != nil:
+		entry.err = err.Error()
+		if ttl > robotsRetryTTL {
+			ttl = robotsRetryTTL
+		}
+	case resp.StatusCode == 200:
+		entry.statusCode = resp.StatusCode
+		entry.rules = parseRobots(string(resp.body), robotsAgent)
+	default:
+		entry.statusCode = resp.StatusCode
+		if resp.StatusCode >= 500 && ttl > robotsRetryTTL {
+			ttl = robotsRetryTTL
+		}
+	}
+	entry.expiresAt = entry.fetchedAt.Add(ttl)
+
+	b.setCrawlDelay(entry.rules.crawlDelay)
+
+	r.robotsMu.Lock()
+	r.robots[b.host] = entry
+	r.robotsMu.Unlock()
+
+	return entry.rules, nil
+}
+
+// Robots returns every cached robots.txt, sorted by host.
+func (r *RateLimiter) Robots() []RobotsStatus {
+	r.robotsMu.Lock()
+	defer r.robotsMu.Unlock()
+
+	now := time.Now()
+	statuses := make([]RobotsStatus, 0, len(r.robots))
+	for host, entry := range r.robots {
+		s := RobotsStatus{
+			Host:              host,
+			URL:               entry.url,
+			StatusCode:        entry.statusCode,
+			Error:             entry.err,
+			CrawlDelaySeconds: entry.rules.crawlDelay.Seconds(),
+			Allow:             []string{},
+			Disallow:          []string{},
+			FetchedAt:         entry.fetchedAt,
+			ExpiresAt:         entry.expiresAt,
+			Expired:           !now.Before(entry.expiresAt),
+		}
+		for _, rule := range entry.rules.rules {
+			if rule.allow {
+				s.Allow = append(s.Allow, rule.pattern)
+			} else {
+				s.Disallow = append(s.Disallow, rule.pattern)
+			}
+		}
+		statuses = append(statuses, s)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Host < statuses[j].Host })
+	return statuses
+}
@@ -0,0 +1,101 @@
+package limiter
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+// hnRobots is news.ycombinator.com/robots.txt as of December 2025.
+const hnRobots = `User-Agent: *
+Disallow: /x?
+Disallow: /r?
+Disallow: /vote?
+Disallow: /reply?
+Disallow: /submitted?
+Disallow: /submitlink?
+Disallow: /threads?
+Crawl-delay: 30
+`
+
+func TestParseRobotsCrawlDelay(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want time.Duration
+	}{
+		{"hacker news", hnRobots, 30 * time.Second},
+		{"none", "User-agent: *\nDisallow: /private\n", 0},
+		{"fractional", "User-agent: *\nCrawl-delay: 0.5\n", 500 * time.Millisecond},
+		{"invalid", "User-agent: *\nCrawl-delay: soon\n", 0},
+		{"negative", "User-agent: *\nCrawl-delay: -5\n", 0},
+		{"largest wins", "User-agent: *\nCrawl-delay: 5\nCrawl-delay: 10\n", 10 * time.Second},
+		{"other agent only", "User-agent: googlebot\nCrawl-delay: 10\n", 0},
+		{"own group wins", "User-agent: *\nCrawl-delay: 60\n\nUser-agent: superpage-ratelimiter\nCrawl-delay: 2\n", 2 * time.Second},
+		{"case insensitive", "USER-AGENT: *\nCRAWL-DELAY: 3\n", 3 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := parseRobots(tt.body, robotsAgent)
+			if rules.crawlDelay != tt.want {
+				t.Errorf("crawl delay = %v, want %v", rules.crawlDelay, tt.want)
+			}
+		})
+	}
+}
+
+func TestRobotsAllowed(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		url  string
+		want bool
+	}{
+		{"hn front page", hnRobots, "https://news.ycombinator.com/", true},
+		{"hn newest", hnRobots, "https://news.ycombinator.com/newest", true},
+		{"hn item", hnRobots, "https://news.ycombinator.com/item?id=1", true},
+		{"hn user", hnRobots, "https://news.ycombinator.com/user?id=pg", true},
+		{"hn submitted", hnRobots, "https://news.ycombinator.com/submitted?id=pg", false},
+		{"hn vote", hnRobots, "https://news.ycombinator.com/vote?id=1&how=up", false},
+		{"hn path prefix only", hnRobots, "https://news.ycombinator.com/x", true},
+		{"robots.txt itself", "User-agent: *\nDisallow: /\n", "https://example.com/robots.txt", true},
+		{"disallow all", "User-agent: *\nDisallow: /\n", "https://example.com/page", false},
+		{"empty disallow", "User-agent: *\nDisallow:\n", "https://example.com/page", true},
+		{"longest match wins", "User-agent: *\nDisallow: /a\nAllow: /a/b\n", "https://example.com/a/b/c", true},
+		{"longer disallow wins", "User-agent: *\nAllow: /a\nDisallow: /a/b\n", "https://example.com/a/b/c", false},
+		{"allow wins tie", "User-agent: *\nDisallow: /a\nAllow: /a\n", "https://example.com/a", true},
+		{"wildcard", "User-agent: *\nDisallow: /*.pdf\n", "https://example.com/docs/x.pdf", false},
+		{"wildcard no match", "User-agent: *\nDisallow: /*.pdf\n", "https://example.com/docs/x.html", true},
+		{"anchored", "User-agent: *\nDisallow: /*.php$\n", "https://example.com/index.php", false},
+		{"anchored with query", "User-agent: *\nDisallow: /*.php$\n", "https://example.com/index.php?a=1", true},
+		{"other agent ignored", "User-agent: googlebot\nDisallow: /\n", "https://example.com/page", true},
+		{"own group replaces wildcard", "User-agent: *\nDisallow: /\n\nUser-agent: superpage-ratelimiter\nDisallow: /private\n", "https://example.com/page", true},
+		{"comments", "User-agent: * # everyone\nDisallow: /private # keep out\n", "https://example.com/private/x", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rules := parseRobots(tt.body, robotsAgent)
+			if got := rules.allowed(robotsPath(u)); got != tt.want {
+				t.Errorf("allowed(%s) = %v, want %v", robotsPath(u), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrawlDelayRaisesInterval(t *testing.T) {
+	b := newBucket("news.ycombinator.com", time.Second, 1)
+	b.setCrawlDelay(parseRobots(hnRobots, robotsAgent).crawlDelay)
+	if got := b.effectiveInterval(); got != 30*time.Second {
+		t.Errorf("effective interval = %v, want 30s", got)
+	}
+
+	b = newBucket("example.com", time.Minute, 1)
+	b.setCrawlDelay(30 * time.Second)
+	if got := b.effectiveInterval(); got != time.Minute {
+		t.Errorf("effective interval = %v, want the longer --rate of 1m", got)
+	}
+}
@@ -0,0 +1,110 @@
+package limiter
+
+import "time"
+
+// HostState is the part of a host's rate limit state that must outlive the
+// process, so a restart does not let requests through early.
+type HostState struct {
+	Host              string    `json:"host"`
+	Tokens            float64   `json:"tokens"`
+	LastRefill        time.Time `json:"last_refill"`
+	LastFetch         time.Time `json:"last_fetch"`
+	CrawlDelaySeconds float64   `json:"crawl_delay_seconds,omitempty"`
+	BackoffLevel      int       `json:"backoff_level"`
+	Successes         int       `json:"successes,omitempty"`
+	BlockedUntil      time.Time `json:"blocked_until"`
+}
+
+// State returns the state of every host seen so far, sorted by host.
+func (r *RateLimiter) State() []HostState {
+	buckets := r.sortedBuckets()
+	states := make([]HostState, len(buckets))
+	for i, b := range buckets {
+		states[i] = b.state()
+	}
+	return states
+}
+
+// RestoreState sets the hosts' state from a previous run. Intervals and
+// bursts come from the current policy; the tokens earned since LastRefill
+// are credited as usual. It should be called before the first Fetch.
+func (r *RateLimiter) RestoreState(states []HostState) {
+	now := time.Now()
+	for _, s := range states {
+		if s.Host == "" {
+			continue
+		}
+		r.bucketFor(s.Host).restore(s, now)
+	}
+}
+
+// StateChanged returns a channel that receives a value after a token has
+// been consumed or a host's backoff state has changed. Changes that happen
+// before the value is received are coalesced.
+func (r *RateLimiter) StateChanged() <-chan struct{} {
+	return r.stateChanged
+}
+
+// notifyChanged signals a change of state without blocking.
+func (b *bucket) notifyChanged() {
+	if b.changed == nil {
+		return
+	}
+	select {
+	case b.changed <- struct{}{}:
+	default:
+	}
+}
+
+// state returns the bucket's persistent state.
+func (b *bucket) state() HostState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return HostState{
+		Host:              b.host,
+		Tokens:            b.tokens,
+		LastRefill:        b.lastRefill,
+		LastFetch:         b.lastFetch,
+		CrawlDelaySeconds: b.crawlDelay.Seconds(),
+		BackoffLevel:      b.backoffLevel,
+		Successes:         b.successes,
+		BlockedUntil:      b.blockedUntil,
+	}
+}
+
+// restore sets the bucket's state from s. Times in the future, e.g. after
+// the clock was set back, are treated as now.
+func (b *bucket) restore(s HostState, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = s.Tokens
+	if b.tokens < 0 {
+		b.tokens = 0
+	}
+	if b.tokens > float64(b.burst) {
+		b.tokens = float64(b.burst)
+	}
+	b.lastRefill = s.LastRefill
+	if b.lastRefill.IsZero() || b.lastRefill.After(now) {
+		b.lastRefill = now
+	}
+	b.lastFetch = s.LastFetch
+	if b.lastFetch.After(now) {
+		b.lastFetch = now
+	}
+
+	b.crawlDelay = time.Duration(s.CrawlDelaySeconds * float64(time.Second))
+	b.backoffLevel = s.BackoffLevel
+	if b.backoffLevel < 0 {
+		b.backoffLevel = 0
+	}
+	if b.backoffLevel > maxBackoffLevel {
+		b.backoffLevel = maxBackoffLevel
+	}
+	b.successes = s.Successes
+	b.blockedUntil = s.BlockedUntil
+	if b.blockedUntil.After(now.Add(maxRetryAfter)) {
+		b.blockedUntil = now.Add(maxRetryAfter)
+	}
+}
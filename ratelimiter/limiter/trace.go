@@ -0,0 +1,131 @@
+package limiter
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Redirect is one hop of a redirect chain.
+type Redirect struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+	Location   string `json:"location"`
+}
+
+// Timings breaks down where the time of a fetch went, in milliseconds. DNS,
+// connect, TLS and TTFB are those of the last hop and are zero for steps
+// that did not happen, e.g. when a kept-alive connection was reused.
+type Timings struct {
+	WaitMs           float64 `json:"wait_ms"`
+	DNSMs            float64 `json:"dns_ms"`
+	ConnectMs        float64 `json:"connect_ms"`
+	TLSMs            float64 `json:"tls_ms"`
+	TTFBMs           float64 `json:"ttfb_ms"`
+	TotalMs          float64 `json:"total_ms"`
+	ReusedConnection bool    `json:"reused_connection"`
+}
+
+// traceKey is the context key of the fetchTrace of a request.
+type traceKey struct{}
+
+// fetchTrace collects the redirects and timings of one get call. Its
+// callbacks may run on several goroutines.
+type fetchTrace struct {
+	hopStart     time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	timings      Timings
+	redirects    []Redirect
+	mu           sync.Mutex
+}
+
+// withTrace returns a context that makes the client report to a new fetchTrace.
+func withTrace(ctx context.Context) (context.Context, *fetchTrace) {
+	t := &fetchTrace{}
+	ctx = context.WithValue(ctx, traceKey{}, t)
+	return httptrace.WithClientTrace(ctx, t.clientTrace()), t
+}
+
+// traceFrom returns the fetchTrace of the request, if any.
+func traceFrom(req *http.Request) *fetchTrace {
+	t, _ := req.Context().Value(traceKey{}).(*fetchTrace)
+	return t
+}
+
+// clientTrace returns the httptrace hooks feeding t.
+func (t *fetchTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GetConn: func(string) {
+			// A new hop starts; forget the previous one's steps
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.hopStart = time.Now()
+			t.timings.DNSMs, t.timings.ConnectMs, t.timings.TLSMs, t.timings.TTFBMs = 0, 0, 0, 0
+			t.timings.ReusedConnection = false
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.timings.ReusedConnection = info.Reused
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.timings.DNSMs = ms(time.Since(t.dnsStart))
+		},
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.connectStart = time.Now()
+		},
+		ConnectDone: func(string, string, error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.timings.ConnectMs = ms(time.Since(t.connectStart))
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.timings.TLSMs = ms(time.Since(t.tlsStart))
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.timings.TTFBMs = ms(time.Since(t.hopStart))
+		},
+	}
+}
+
+// addRedirect records a hop of the redirect chain.
+func (t *fetchTrace) addRedirect(r Redirect) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.redirects = append(t.redirects, r)
+}
+
+// result returns the redirects and timings collected so far.
+func (t *fetchTrace) result() ([]Redirect, Timings) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Redirect(nil), t.redirects...), t.timings
+}
+
+// ms converts a duration to fractional milliseconds.
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -1,28 +1,271 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"ratelimiter/api"
+	"ratelimiter/archive"
+	"ratelimiter/jobs"
 	"ratelimiter/limiter"
+	"ratelimiter/state"
 )
 
+// stringList collects repeatable, comma-separated arguments such as hosts,
+// schemes or content types.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			return fmt.Errorf("empty entry in %q", value)
+		}
+		*l = append(*l, entry)
+	}
+	return nil
+}
+
+// hostRates collects repeatable --host-rate <host>=<num-sec> arguments.
+type hostRates map[string]int
+
+func (h hostRates) String() string {
+	var parts []string
+	for host, secs := range h {
+		parts = append(parts, fmt.Sprintf("%s=%d", host, secs))
+	}
+	return strings.Join(parts, ",")
+}
+
+func (h hostRates) Set(value string) error {
+	for _, entry := range strings.Split(value, ",") {
+		host, secs, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || host == "" {
+			return fmt.Errorf("expected <host>=<num-sec>, got %q", entry)
+		}
+		n, err := strconv.Atoi(secs)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("rate for %s must be a positive integer", host)
+		}
+		h[strings.ToLower(host)] = n
+	}
+	return nil
+}
+
+// clientWeights collects repeatable --client-weight <client>=<weight> arguments.
+type clientWeights map[string]float64
+
+func (c clientWeights) String() string {
+	var parts []string
+	for client, weight := range c {
+		parts = append(parts, fmt.Sprintf("%s=%g", client, weight))
+	}
+	return strings.Join(parts, ",")
+}
+
+func (c clientWeights) Set(value string) error {
+	for _, entry := range strings.Split(value, ",") {
+		client, weight, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || client == "" {
+			return fmt.Errorf("expected <client>=<weight>, got %q", entry)
+		}
+		w, err := strconv.ParseFloat(weight, 64)
+		if err != nil || w <= 0 {
+			return fmt.Errorf("weight for %s must be a positive number", client)
+		}
+		c[client] = w
+	}
+	return nil
+}
+
+// clientQuotas collects repeatable --client-quota <client>=<requests> arguments.
+type clientQuotas map[string]int
+
+func (c clientQuotas) String() string {
+	var parts []string
+	for client, quota := range c {
+		parts = append(parts, fmt.Sprintf("%s=%d", client, quota))
+	}
+	return strings.Join(parts, ",")
+}
+
+func (c clientQuotas) Set(value string) error {
+	for _, entry := range strings.Split(value, ",") {
+		client, quota, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || client == "" {
+			return fmt.Errorf("expected <client>=<requests>, got %q", entry)
+		}
+		n, err := strconv.Atoi(quota)
+		if err != nil || n < 0 {
+			return fmt.Errorf("quota for %s must be a non-negative integer (0 means no limit)", client)
+		}
+		c[client] = n
+	}
+	return nil
+}
+
 func main() {
-	rate := flag.Int("rate", 0, "Minimum number of seconds between HTTP requests (required, must be positive)")
+	rate := flag.Int("rate", 0, "Minimum number of seconds between HTTP requests to the same host (required unless --refill-every is set, must be positive)")
 	port := flag.Int("api", 0, "Port number for the REST API (required)")
+	perHost := hostRates{}
+	flag.Var(perHost, "host-rate", "Per-host override as <host>=<num-sec> (repeatable, or comma-separated)")
+	burst := flag.Int("burst", 1, "Number of requests a host may receive back to back (token-bucket capacity)")
+	refillEvery := flag.Duration("refill-every", 0, "Time to earn one token, e.g. 30s (alternative to --rate)")
+	obeyRobots := flag.Bool("robots", true, "Obey robots.txt: refuse disallowed paths and honor Crawl-delay")
+	robotsTTL := flag.Duration("robots-ttl", 24*time.Hour, "How long a fetched robots.txt is cached")
+	cacheSize := flag.Int("cache-size", 500, "Number of responses cached for conditional requests and max_age (0 disables)")
+	cacheMaxBytes := flag.Int64("cache-max-bytes", 64<<20, "Maximum total size of the cached response bodies, in bytes; larger bodies are not cached (0 means no limit)")
+	jobRetention := flag.Duration("job-retention", time.Hour, "How long finished jobs are kept for GET /jobs/{id}")
+	archiveDir := flag.String("archive-dir", "", "Directory to write a WARC archive of every request and response to (disabled if empty)")
+	archiveMaxSize := flag.Int("archive-max-size", 1024, "Size in MB after which a new WARC file is started")
+	var allowSchemes, allowHosts, denyHosts, allowContentTypes, allowMethods stringList
+	flag.Var(&allowSchemes, "allow-schemes", "URL schemes that may be fetched (repeatable, or comma-separated; default http,https)")
+	flag.Var(&allowHosts, "allow-hosts", "Only fetch these hosts and their subdomains (repeatable, or comma-separated; default any host)")
+	flag.Var(&denyHosts, "deny-hosts", "Never fetch these hosts or their subdomains (repeatable, or comma-separated)")
+	maxBodySize := flag.Int64("max-body-size", 10<<20, "Maximum number of body bytes read from a response; the rest is dropped and the result marked truncated (0 means no limit)")
+	flag.Var(&allowContentTypes, "allow-content-types", "Media types a response may have, e.g. text/html or text/* (repeatable, or comma-separated; default any)")
+	flag.Var(&allowMethods, "allow-methods", "HTTP methods that may be sent (repeatable, or comma-separated; default GET,HEAD,POST)")
+	maxRequestBodySize := flag.Int64("max-request-body-size", 1<<20, "Maximum size of a request body sent upstream, in bytes (0 means no limit)")
+	allowPrivate := flag.Bool("allow-private", false, "Allow fetching loopback, private, link-local and other internal addresses")
+	replayDir := flag.String("replay", "", "Serve responses from the archive in this directory instead of the network")
+	replayStrategy := flag.String("replay-strategy", "sequential", "Which recorded response answers a request: sequential or nearest")
+	replayAt := flag.String("replay-at", "", "Time the nearest strategy aims for, in RFC 3339 (default now)")
+	weights := clientWeights{}
+	flag.Var(weights, "client-weight", "Share of each host's turns for a client (X-Client-ID) as <client>=<weight>, default 1 (repeatable, or comma-separated)")
+	quotas := clientQuotas{}
+	flag.Var(quotas, "client-quota", "Daily request quota for a client as <client>=<requests>, 0 for no limit (repeatable, or comma-separated)")
+	defaultQuota := flag.Int("default-quota", 0, "Daily request quota of anonymous requests, and of all clients without their own weight or quota together (0 means no limit)")
+	breakerThreshold := flag.Int("breaker-threshold", 5, "Consecutive network errors or 5xx responses after which a host's circuit opens (0 disables the circuit breaker)")
+	breakerCooldown := flag.Duration("breaker-cooldown", time.Minute, "How long a host's circuit stays open before a probe request is let through")
+	stateFile := flag.String("state-file", "", "File the per-host last fetch, tokens and backoff are saved to and restored from on startup (disabled if empty)")
 	flag.Parse()
 
 	// Validate required arguments
-	if *rate <= 0 {
-		fmt.Fprintln(os.Stderr, "Error: --rate must be a positive integer")
+	if *rate != 0 && *refillEvery != 0 {
+		fmt.Fprintln(os.Stderr, "Error: use either --rate or --refill-every, not both")
 		flag.Usage()
 		os.Exit(1)
 	}
 
+	interval := time.Duration(*rate) * time.Second
+	if *refillEvery != 0 {
+		interval = *refillEvery
+	}
+	if interval < 0 || (interval == 0 && *replayDir == "") {
+		fmt.Fprintln(os.Stderr, "Error: --rate must be a positive integer (or --refill-every a positive duration)")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if *burst < 1 {
+		fmt.Fprintln(os.Stderr, "Error: --burst must be a positive integer")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if *robotsTTL <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: --robots-ttl must be a positive duration")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if *cacheSize < 0 {
+		fmt.Fprintln(os.Stderr, "Error: --cache-size must not be negative")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if *cacheMaxBytes < 0 {
+		fmt.Fprintln(os.Stderr, "Error: --cache-max-bytes must not be negative")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if *jobRetention <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: --job-retention must be a positive duration")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if *archiveMaxSize <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: --archive-max-size must be a positive integer")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if *maxBodySize < 0 {
+		fmt.Fprintln(os.Stderr, "Error: --max-body-size must not be negative")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if *defaultQuota < 0 {
+		fmt.Fprintln(os.Stderr, "Error: --default-quota must not be negative")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if *breakerThreshold < 0 {
+		fmt.Fprintln(os.Stderr, "Error: --breaker-threshold must not be negative")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if *breakerCooldown <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: --breaker-cooldown must be a positive duration")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if *maxRequestBodySize < 0 {
+		fmt.Fprintln(os.Stderr, "Error: --max-request-body-size must not be negative")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// Methods are sent and reported in upper case
+	for i, method := range allowMethods {
+		allowMethods[i] = strings.ToUpper(method)
+	}
+
+	if *archiveDir != "" && *replayDir != "" {
+		fmt.Fprintln(os.Stderr, "Error: use either --archive-dir or --replay, not both")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	strategy, err := archive.ParseStrategy(*replayStrategy)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --replay-strategy: %v\n", err)
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	at := time.Now()
+	if *replayAt != "" {
+		at, err = time.Parse(time.RFC3339, *replayAt)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error: --replay-at must be an RFC 3339 time, e.g. 2025-12-05T10:30:00Z")
+			flag.Usage()
+			os.Exit(1)
+		}
+	}
+
 	if *port <= 0 || *port > 65535 {
 		fmt.Fprintln(os.Stderr, "Error: --api must be a valid port number (1-65535)")
 		flag.Usage()
@@ -30,19 +273,185 @@ func main() {
 	}
 
 	// Initialize the rate limiter
-	rl := limiter.New(*rate)
+	policy := limiter.Policy{
+		DefaultInterval:     interval,
+		HostIntervals:       make(map[string]time.Duration, len(perHost)),
+		Burst:               *burst,
+		ObeyRobots:          *obeyRobots && *replayDir == "",
+		RobotsTTL:           *robotsTTL,
+		CacheSize:           *cacheSize,
+		CacheMaxBytes:       *cacheMaxBytes,
+		AllowedSchemes:      allowSchemes,
+		AllowedHosts:        allowHosts,
+		DeniedHosts:         denyHosts,
+		AllowPrivate:        *allowPrivate,
+		MaxBodySize:         *maxBodySize,
+		AllowedContentTypes: allowContentTypes,
+		AllowedMethods:      allowMethods,
+		MaxRequestBodySize:  *maxRequestBodySize,
+		ClientWeights:       weights,
+		ClientQuotas:        quotas,
+		DefaultQuota:        *defaultQuota,
+		BreakerThreshold:    *breakerThreshold,
+		BreakerCooldown:     *breakerCooldown,
+	}
+	// A replay has no host that could be down, and a URL missing from the
+	// archive must not open a circuit
+	if *replayDir != "" {
+		policy.BreakerThreshold = 0
+	}
+	for host, secs := range perHost {
+		policy.HostIntervals[host] = time.Duration(secs) * time.Second
+	}
+	rl := limiter.New(policy)
+
+	// Archive every response if requested. Each record is written whole, so
+	// the files stay readable even if the process is killed.
+	var aw *archive.Writer
+	if *archiveDir != "" {
+		aw, err = archive.NewWriter(*archiveDir, int64(*archiveMaxSize)<<20)
+		if err != nil {
+			log.Fatalf("Failed to open archive: %v", err)
+		}
+		rl.SetRecorder(aw)
+	}
+
+	// In replay mode nothing reaches the network, so robots.txt is not
+	// consulted and pacing is off unless --rate or --refill-every is given
+	var replayer *archive.Replayer
+	if *replayDir != "" {
+		replayer, err = archive.NewReplayer(*replayDir, strategy, at)
+		if err != nil {
+			log.Fatalf("Failed to open archive: %v", err)
+		}
+		rl.SetTransport(replayer)
+	}
+
+	// Restore the hosts' state from the previous run, so a restart does not
+	// let requests through early. A replay must not touch the real state.
+	var store *state.Store
+	if *stateFile != "" && *replayDir == "" {
+		store = state.NewStore(rl, *stateFile)
+		n, err := store.Load()
+		if err != nil {
+			log.Fatalf("Failed to load state: %v", err)
+		}
+		log.Printf("Restored the state of %d hosts from %s", n, *stateFile)
+		store.Start()
+	}
+
+	// Initialize the job manager for asynchronous fetches
+	jm := jobs.NewManager(rl, *jobRetention, api.ErrorCode)
+	jm.Start()
 
 	// Initialize the API handler
-	handler := api.NewHandler(rl)
+	handler := api.NewHandler(rl, jm)
 
 	// Set up routes
 	http.HandleFunc("/fetch", handler.HandleFetch)
+	http.HandleFunc("/jobs", handler.HandleJobs)
+	http.HandleFunc("/jobs/", handler.HandleJob)
+	http.HandleFunc("/hosts", handler.HandleHosts)
+	http.HandleFunc("/queue", handler.HandleQueue)
+	http.HandleFunc("/status", handler.HandleStatus)
+	http.HandleFunc("/metrics", handler.HandleMetrics)
+	http.HandleFunc("/admin/robots", handler.HandleRobots)
+	http.HandleFunc("/admin/policy", handler.HandlePolicy)
+	http.HandleFunc("/admin/pause", handler.HandlePause)
+	http.HandleFunc("/admin/resume", handler.HandleResume)
 	http.HandleFunc("/doc", handler.HandleDoc)
 
-	// Start the server
-	addr := fmt.Sprintf(":%d", *port)
-	log.Printf("Starting Rate Limiter API server on port %d (rate limit: 1 request per %d seconds)", *port, *rate)
-	if err := http.ListenAndServe(addr, nil); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	// Start the server. Requests get a context that shutdown cancels, and
+	// are counted so shutdown can wait for their handlers to return
+	requestCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+	var inFlight sync.WaitGroup
+	server := &http.Server{
+		Addr:        fmt.Sprintf(":%d", *port),
+		Handler:     trackRequests(&inFlight, http.DefaultServeMux),
+		BaseContext: func(net.Listener) context.Context { return requestCtx },
+	}
+	log.Printf("Starting Rate Limiter API server on port %d (%s: 1 token per %v per host, burst %d)", *port, policy.Mode(), interval, *burst)
+	for host, secs := range perHost {
+		log.Printf("  %s: 1 token per %d seconds", host, secs)
+	}
+	if policy.ObeyRobots {
+		log.Printf("Obeying robots.txt (cached for %v)", *robotsTTL)
+	}
+	if *archiveDir != "" {
+		log.Printf("Archiving responses to %s (new WARC file every %d MB)", *archiveDir, *archiveMaxSize)
+	}
+	if len(allowHosts) > 0 {
+		log.Printf("Only fetching hosts: %s", allowHosts.String())
+	}
+	for client, weight := range weights {
+		log.Printf("  client %s: weight %g", client, weight)
 	}
+	for client, quota := range quotas {
+		log.Printf("  client %s: %d requests per day", client, quota)
+	}
+	if *defaultQuota > 0 {
+		log.Printf("Anonymous and other clients: %d requests per day each", *defaultQuota)
+	}
+	if policy.BreakerThreshold > 0 {
+		log.Printf("Opening a host's circuit after %d consecutive failures (probing again after %v)", policy.BreakerThreshold, *breakerCooldown)
+	}
+	if *allowPrivate {
+		log.Printf("Warning: fetching internal addresses is allowed (--allow-private)")
+	}
+	if replayer != nil {
+		log.Printf("Replaying %d recorded responses from %s (%s strategy)", replayer.Len(), *replayDir, strategy)
+	}
+	go func() {
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	}()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-sigCh
+	log.Printf("Received signal %v, shutting down...", sig)
+
+	// Give requests in progress a moment to finish. Shutdown does not stop
+	// the handlers still running after that, so cancel their requests, close
+	// their connections and wait for them to return
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("HTTP server shutdown error: %v; cancelling the requests still in progress", err)
+		cancelRequests()
+		server.Close()
+	}
+	inFlight.Wait()
+	jm.Stop()
+
+	// Every handler and job has returned, so nothing records into the
+	// archive any more and the last WARC file can be closed without cutting
+	// off a record
+	if aw != nil {
+		if err := aw.Close(); err != nil {
+			log.Printf("Failed to close archive: %v", err)
+		}
+	}
+
+	// Save the state last, after every request has finished
+	if store != nil {
+		if err := store.Stop(); err != nil {
+			log.Printf("%v", err)
+		} else {
+			log.Printf("Saved the state of the rate limiter to %s", *stateFile)
+		}
+	}
+	log.Printf("Rate Limiter shutdown complete")
+}
+
+// trackRequests counts the requests h is serving in wg, so shutdown can wait
+// for every handler to return.
+func trackRequests(wg *sync.WaitGroup, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wg.Add(1)
+		defer wg.Done()
+		h.ServeHTTP(w, r)
+	})
 }
@@ -0,0 +1,126 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"ratelimiter/limiter"
+)
+
+// fileVersion is written to every state file; files with another version
+// are refused.
+const fileVersion = 1
+
+// File is the content of a state file.
+type File struct {
+	Version int                 `json:"version"`
+	SavedAt time.Time           `json:"saved_at"`
+	Hosts   []limiter.HostState `json:"hosts"`
+}
+
+// Store keeps a rate limiter's per-host state in a file: the last fetch,
+// the tokens and the backoff of every host. It is saved whenever a token is
+// consumed or the backoff changes, so even a crash loses next to nothing.
+type Store struct {
+	rateLimiter *limiter.RateLimiter
+	path        string
+
+	stopCh    chan struct{}
+	stoppedCh chan struct{}
+}
+
+// NewStore creates a Store for rl's state in the file at path.
+func NewStore(rl *limiter.RateLimiter, path string) *Store {
+	return &Store{
+		rateLimiter: rl,
+		path:        path,
+		stopCh:      make(chan struct{}),
+		stoppedCh:   make(chan struct{}),
+	}
+}
+
+// Load restores the state saved in the file and returns the number of hosts
+// restored. A missing file restores nothing and is not an error.
+func (s *Store) Load() (int, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	var f File
+	if err := json.Unmarshal(data, &f); err != nil {
+		return 0, fmt.Errorf("failed to parse state file %s: %w", s.path, err)
+	}
+	if f.Version != fileVersion {
+		return 0, fmt.Errorf("state file %s has version %d, expected %d", s.path, f.Version, fileVersion)
+	}
+
+	s.rateLimiter.RestoreState(f.Hosts)
+	return len(f.Hosts), nil
+}
+
+// Save writes the current state to the file. The file is replaced
+// atomically, so a crash leaves either the old or the new state.
+func (s *Store) Save() error {
+	data, err := json.MarshalIndent(File{
+		Version: fileVersion,
+		SavedAt: time.Now(),
+		Hosts:   s.rateLimiter.State(),
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	return nil
+}
+
+// Start starts saving the state in the background whenever it changes.
+func (s *Store) Start() {
+	go s.run()
+}
+
+// Stop stops the background saving and saves the state one last time.
+func (s *Store) Stop() error {
+	close(s.stopCh)
+	<-s.stoppedCh
+	return s.Save()
+}
+
+// run saves the state after every change until Stop is called.
+func (s *Store) run() {
+	defer close(s.stoppedCh)
+
+	for {
+		select {
+		case <-s.rateLimiter.StateChanged():
+			if err := s.Save(); err != nil {
+				log.Printf("%v", err)
+			}
+		case <-s.stopCh:
+			return
+		}
+	}
+}
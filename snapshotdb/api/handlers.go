@@ -82,17 +82,19 @@ func (h *Handler) handleSnapshots(w http.ResponseWriter, r *http.Request) {
 		stories := make([]StoryDTO, len(snap.Stories))
 		for j, s := range snap.Stories {
 			stories[j] = StoryDTO{
-				StoryID:       s.StoryID,
-				Rank:          s.Rank,
-				Headline:      s.Headline,
-				URL:           s.URL,
-				Username:      s.Username,
-				Points:        s.Points,
-				Comments:      s.Comments,
-				DiscussionURL: s.DiscussionURL,
-				AgeValue:      s.AgeValue,
-				AgeUnit:       s.AgeUnit,
-				Page:          s.Page,
+				StoryID:              s.StoryID,
+				Rank:                 s.Rank,
+				Headline:             s.Headline,
+				URL:                  s.URL,
+				Username:             s.Username,
+				Points:               s.Points,
+				Comments:             s.Comments,
+				DiscussionURL:        s.DiscussionURL,
+				AgeValue:             s.AgeValue,
+				AgeUnit:              s.AgeUnit,
+				Page:                 s.Page,
+				SubmittedAt:          unixOrNil(s.SubmittedAt),
+				SubmittedAtEstimated: s.SubmittedAtEstimated,
 			}
 		}
 		dtos[i] = SnapshotDTO{
@@ -167,18 +169,20 @@ func (h *Handler) handleStory(w http.ResponseWriter, r *http.Request) {
 	dtos := make([]StoryOccurrenceDTO, len(occurrences))
 	for i, occ := range occurrences {
 		dtos[i] = StoryOccurrenceDTO{
-			SnapshotID:    occ.SnapshotID,
-			FetchedAt:     occ.FetchedAt.Unix(),
-			Rank:          occ.Rank,
-			Headline:      occ.Headline,
-			URL:           occ.URL,
-			Username:      occ.Username,
-			Points:        occ.Points,
-			Comments:      occ.Comments,
-			DiscussionURL: occ.DiscussionURL,
-			AgeValue:      occ.AgeValue,
-			AgeUnit:       occ.AgeUnit,
-			Page:          occ.Page,
+			SnapshotID:           occ.SnapshotID,
+			FetchedAt:            occ.FetchedAt.Unix(),
+			Rank:                 occ.Rank,
+			Headline:             occ.Headline,
+			URL:                  occ.URL,
+			Username:             occ.Username,
+			Points:               occ.Points,
+			Comments:             occ.Comments,
+			DiscussionURL:        occ.DiscussionURL,
+			AgeValue:             occ.AgeValue,
+			AgeUnit:              occ.AgeUnit,
+			Page:                 occ.Page,
+			SubmittedAt:          unixOrNil(occ.SubmittedAt),
+			SubmittedAtEstimated: occ.SubmittedAtEstimated,
 		}
 	}
 
@@ -253,6 +257,7 @@ func (h *Handler) handleDoc(w http.ResponseWriter, r *http.Request) {
 										AgeValue:      5,
 										AgeUnit:       "hours",
 										Page:          1,
+										SubmittedAt:   ptrInt64(1702364400),
 									},
 								},
 							},
@@ -311,6 +316,7 @@ func (h *Handler) handleDoc(w http.ResponseWriter, r *http.Request) {
 								AgeValue:      5,
 								AgeUnit:       "hours",
 								Page:          1,
+								SubmittedAt:   ptrInt64(1702364400),
 							},
 							{
 								SnapshotID:    2,
@@ -325,6 +331,7 @@ func (h *Handler) handleDoc(w http.ResponseWriter, r *http.Request) {
 								AgeValue:      5,
 								AgeUnit:       "hours",
 								Page:          1,
+								SubmittedAt:   ptrInt64(1702364400),
 							},
 						},
 					},
@@ -395,3 +402,11 @@ func (e *paramError) Error() string {
 func ptrInt64(v int64) *int64 {
 	return &v
 }
+
+// unixOrNil returns t as Unix seconds, or nil if t is nil
+func unixOrNil(t *time.Time) *int64 {
+	if t == nil {
+		return nil
+	}
+	return ptrInt64(t.Unix())
+}
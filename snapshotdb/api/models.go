@@ -22,17 +22,19 @@ type SnapshotDTO struct {
 }
 
 type StoryDTO struct {
-	StoryID       string `json:"story_id"`
-	Rank          int    `json:"rank"`
-	Headline      string `json:"headline"`
-	URL           string `json:"url"`
-	Username      string `json:"username"`
-	Points        int    `json:"points"`
-	Comments      int    `json:"comments"`
-	DiscussionURL string `json:"discussion_url"`
-	AgeValue      int    `json:"age_value"`
-	AgeUnit       string `json:"age_unit"`
-	Page          int    `json:"page"`
+	StoryID              string `json:"story_id"`
+	Rank                 int    `json:"rank"`
+	Headline             string `json:"headline"`
+	URL                  string `json:"url"`
+	Username             string `json:"username"`
+	Points               int    `json:"points"`
+	Comments             int    `json:"comments"`
+	DiscussionURL        string `json:"discussion_url"`
+	AgeValue             int    `json:"age_value"`
+	AgeUnit              string `json:"age_unit"`
+	Page                 int    `json:"page"`
+	SubmittedAt          *int64 `json:"submitted_at"`
+	SubmittedAtEstimated bool   `json:"submitted_at_estimated"`
 }
 
 type StoriesResponse struct {
@@ -46,18 +48,20 @@ type StoryResponse struct {
 }
 
 type StoryOccurrenceDTO struct {
-	SnapshotID    int64  `json:"snapshot_id"`
-	FetchedAt     int64  `json:"fetched_at"`
-	Rank          int    `json:"rank"`
-	Headline      string `json:"headline"`
-	URL           string `json:"url"`
-	Username      string `json:"username"`
-	Points        int    `json:"points"`
-	Comments      int    `json:"comments"`
-	DiscussionURL string `json:"discussion_url"`
-	AgeValue      int    `json:"age_value"`
-	AgeUnit       string `json:"age_unit"`
-	Page          int    `json:"page"`
+	SnapshotID           int64  `json:"snapshot_id"`
+	FetchedAt            int64  `json:"fetched_at"`
+	Rank                 int    `json:"rank"`
+	Headline             string `json:"headline"`
+	URL                  string `json:"url"`
+	Username             string `json:"username"`
+	Points               int    `json:"points"`
+	Comments             int    `json:"comments"`
+	DiscussionURL        string `json:"discussion_url"`
+	AgeValue             int    `json:"age_value"`
+	AgeUnit              string `json:"age_unit"`
+	Page                 int    `json:"page"`
+	SubmittedAt          *int64 `json:"submitted_at"`
+	SubmittedAtEstimated bool   `json:"submitted_at_estimated"`
 }
 
 type ErrorResponse struct {
@@ -10,6 +10,11 @@ import (
 	"snapshotdb/store"
 )
 
+// parserTimeout bounds a single fetch from the Parser. The Rate Limiter
+// obeys Hacker News' robots.txt Crawl-delay of 30 seconds, so a fetch of N
+// pages takes about 30*N seconds, plus one interval for robots.txt itself.
+const parserTimeout = 10 * time.Minute
+
 type Client struct {
 	baseURL    string
 	httpClient *http.Client
@@ -23,24 +28,26 @@ type FetchResponse struct {
 }
 
 type Story struct {
-	ID            string `json:"id"`
-	Rank          int    `json:"rank"`
-	Headline      string `json:"headline"`
-	URL           string `json:"url"`
-	Username      string `json:"username"`
-	Points        int    `json:"points"`
-	Comments      int    `json:"comments"`
-	DiscussionURL string `json:"discussion_url"`
-	AgeValue      int    `json:"age_value"`
-	AgeUnit       string `json:"age_unit"`
-	Page          int    `json:"page"`
+	ID                   string `json:"id"`
+	Rank                 int    `json:"rank"`
+	Headline             string `json:"headline"`
+	URL                  string `json:"url"`
+	Username             string `json:"username"`
+	Points               int    `json:"points"`
+	Comments             int    `json:"comments"`
+	DiscussionURL        string `json:"discussion_url"`
+	AgeValue             int    `json:"age_value"`
+	AgeUnit              string `json:"age_unit"`
+	SubmittedAtUnix      int64  `json:"submitted_at_unix"`
+	SubmittedAtEstimated bool   `json:"submitted_at_estimated"`
+	Page                 int    `json:"page"`
 }
 
 func NewClient(baseURL string) *Client {
 	return &Client{
 		baseURL: baseURL,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout: parserTimeout,
 		},
 	}
 }
@@ -116,6 +123,11 @@ func (c *Client) doFetch() (*store.Snapshot, error) {
 			AgeUnit:       s.AgeUnit,
 			Page:          s.Page,
 		}
+		if s.SubmittedAtUnix > 0 {
+			submittedAt := time.Unix(s.SubmittedAtUnix, 0).UTC()
+			stories[i].SubmittedAt = &submittedAt
+			stories[i].SubmittedAtEstimated = s.SubmittedAtEstimated
+		}
 	}
 
 	return &store.Snapshot{
@@ -20,19 +20,21 @@ type Snapshot struct {
 }
 
 type Story struct {
-	ID            int64
-	SnapshotID    int64
-	StoryID       string
-	Rank          int
-	Headline      string
-	URL           string
-	Username      string
-	Points        int
-	Comments      int
-	DiscussionURL string
-	AgeValue      int
-	AgeUnit       string
-	Page          int
+	ID                   int64
+	SnapshotID           int64
+	StoryID              string
+	Rank                 int
+	Headline             string
+	URL                  string
+	Username             string
+	Points               int
+	Comments             int
+	DiscussionURL        string
+	AgeValue             int
+	AgeUnit              string
+	Page                 int
+	SubmittedAt          *time.Time
+	SubmittedAtEstimated bool
 }
 
 func New(dbPath string) (*Store, error) {
@@ -76,6 +78,8 @@ func (s *Store) createTables() error {
 			age_value INTEGER NOT NULL,
 			age_unit TEXT NOT NULL,
 			page INTEGER NOT NULL,
+			submitted_at DATETIME,
+			submitted_at_estimated INTEGER NOT NULL DEFAULT 0,
 			FOREIGN KEY (snapshot_id) REFERENCES snapshots(id)
 		);
 
@@ -83,7 +87,47 @@ func (s *Store) createTables() error {
 		CREATE INDEX IF NOT EXISTS idx_stories_snapshot_id ON stories(snapshot_id);
 		CREATE INDEX IF NOT EXISTS idx_stories_story_id ON stories(story_id);
 	`)
-	return err
+	if err != nil {
+		return err
+	}
+	return s.migrate()
+}
+
+// migrate adds the columns introduced after the first release to databases
+// created before them
+func (s *Store) migrate() error {
+	columns := map[string]bool{}
+	rows, err := s.db.Query("PRAGMA table_info(stories)")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, typ string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err != nil {
+			return err
+		}
+		columns[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	added := []struct{ name, def string }{
+		{"submitted_at", "DATETIME"},
+		{"submitted_at_estimated", "INTEGER NOT NULL DEFAULT 0"},
+	}
+	for _, col := range added {
+		if columns[col.name] {
+			continue
+		}
+		if _, err := s.db.Exec("ALTER TABLE stories ADD COLUMN " + col.name + " " + col.def); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (s *Store) SaveSnapshot(snapshot *Snapshot) error {
@@ -107,8 +151,8 @@ func (s *Store) SaveSnapshot(snapshot *Snapshot) error {
 	}
 
 	stmt, err := tx.Prepare(`
-		INSERT INTO stories (snapshot_id, story_id, rank, headline, url, username, points, comments, discussion_url, age_value, age_unit, page)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO stories (snapshot_id, story_id, rank, headline, url, username, points, comments, discussion_url, age_value, age_unit, page, submitted_at, submitted_at_estimated)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`)
 	if err != nil {
 		return err
@@ -120,6 +164,7 @@ func (s *Store) SaveSnapshot(snapshot *Snapshot) error {
 			snapshotID, story.StoryID, story.Rank, story.Headline, story.URL,
 			story.Username, story.Points, story.Comments, story.DiscussionURL,
 			story.AgeValue, story.AgeUnit, story.Page,
+			story.SubmittedAt, story.SubmittedAtEstimated,
 		)
 		if err != nil {
 			return err
@@ -177,7 +222,8 @@ func (s *Store) GetSnapshotsInRange(from, to time.Time) ([]Snapshot, error) {
 
 func (s *Store) getStoriesForSnapshot(snapshotID int64) ([]Story, error) {
 	rows, err := s.db.Query(`
-		SELECT id, snapshot_id, story_id, rank, headline, url, username, points, comments, discussion_url, age_value, age_unit, page
+		SELECT id, snapshot_id, story_id, rank, headline, url, username, points, comments, discussion_url, age_value, age_unit, page,
+			submitted_at, submitted_at_estimated
 		FROM stories WHERE snapshot_id = ? ORDER BY rank
 	`, snapshotID)
 	if err != nil {
@@ -188,13 +234,18 @@ func (s *Store) getStoriesForSnapshot(snapshotID int64) ([]Story, error) {
 	var stories []Story
 	for rows.Next() {
 		var story Story
+		var submittedAt sql.NullTime
 		if err := rows.Scan(
 			&story.ID, &story.SnapshotID, &story.StoryID, &story.Rank, &story.Headline,
 			&story.URL, &story.Username, &story.Points, &story.Comments, &story.DiscussionURL,
 			&story.AgeValue, &story.AgeUnit, &story.Page,
+			&submittedAt, &story.SubmittedAtEstimated,
 		); err != nil {
 			return nil, err
 		}
+		if submittedAt.Valid {
+			story.SubmittedAt = &submittedAt.Time
+		}
 		stories = append(stories, story)
 	}
 
@@ -230,7 +281,8 @@ func (s *Store) GetStoryInRange(storyID string, from, to time.Time) ([]StoryOccu
 	rows, err := s.db.Query(`
 		SELECT stories.id, stories.snapshot_id, snapshots.fetched_at, stories.rank, stories.headline,
 			stories.url, stories.username, stories.points, stories.comments, stories.discussion_url,
-			stories.age_value, stories.age_unit, stories.page
+			stories.age_value, stories.age_unit, stories.page,
+			stories.submitted_at, stories.submitted_at_estimated
 		FROM stories
 		JOIN snapshots ON stories.snapshot_id = snapshots.id
 		WHERE stories.story_id = ? AND snapshots.fetched_at >= ? AND snapshots.fetched_at <= ?
@@ -244,13 +296,18 @@ func (s *Store) GetStoryInRange(storyID string, from, to time.Time) ([]StoryOccu
 	var occurrences []StoryOccurrence
 	for rows.Next() {
 		var occ StoryOccurrence
+		var submittedAt sql.NullTime
 		if err := rows.Scan(
 			&occ.ID, &occ.SnapshotID, &occ.FetchedAt, &occ.Rank, &occ.Headline,
 			&occ.URL, &occ.Username, &occ.Points, &occ.Comments, &occ.DiscussionURL,
 			&occ.AgeValue, &occ.AgeUnit, &occ.Page,
+			&submittedAt, &occ.SubmittedAtEstimated,
 		); err != nil {
 			return nil, err
 		}
+		if submittedAt.Valid {
+			occ.SubmittedAt = &submittedAt.Time
+		}
 		occurrences = append(occurrences, occ)
 	}
 
@@ -258,19 +315,21 @@ func (s *Store) GetStoryInRange(storyID string, from, to time.Time) ([]StoryOccu
 }
 
 type StoryOccurrence struct {
-	ID            int64
-	SnapshotID    int64
-	FetchedAt     time.Time
-	Rank          int
-	Headline      string
-	URL           string
-	Username      string
-	Points        int
-	Comments      int
-	DiscussionURL string
-	AgeValue      int
-	AgeUnit       string
-	Page          int
+	ID                   int64
+	SnapshotID           int64
+	FetchedAt            time.Time
+	Rank                 int
+	Headline             string
+	URL                  string
+	Username             string
+	Points               int
+	Comments             int
+	DiscussionURL        string
+	AgeValue             int
+	AgeUnit              string
+	Page                 int
+	SubmittedAt          *time.Time
+	SubmittedAtEstimated bool
 }
 
 func (s *Store) Close() error {
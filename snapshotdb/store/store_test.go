@@ -0,0 +1,124 @@
+package store
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// oldSchema is the stories table as created before submission times were
+// stored
+const oldSchema = `
+	CREATE TABLE snapshots (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		fetched_at DATETIME NOT NULL,
+		num_pages INTEGER NOT NULL,
+		total_stories INTEGER NOT NULL
+	);
+
+	CREATE TABLE stories (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		snapshot_id INTEGER NOT NULL,
+		story_id TEXT NOT NULL,
+		rank INTEGER NOT NULL,
+		headline TEXT NOT NULL,
+		url TEXT,
+		username TEXT,
+		points INTEGER NOT NULL,
+		comments INTEGER NOT NULL,
+		discussion_url TEXT,
+		age_value INTEGER NOT NULL,
+		age_unit TEXT NOT NULL,
+		page INTEGER NOT NULL,
+		FOREIGN KEY (snapshot_id) REFERENCES snapshots(id)
+	);
+`
+
+func TestMigrateOldDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshots.db")
+	oldFetch := time.Date(2025, 12, 5, 10, 0, 0, 0, time.UTC)
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(oldSchema); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO snapshots (fetched_at, num_pages, total_stories) VALUES (?, 1, 1)", oldFetch); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO stories (snapshot_id, story_id, rank, headline, url, username, points, comments, discussion_url, age_value, age_unit, page)
+		VALUES (1, '100', 1, 'Old story', 'https://example.com/', 'alice', 10, 2, 'https://news.ycombinator.com/item?id=100', 3, 'hours', 1)`); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	// Opening twice checks that the migration only adds missing columns
+	for i := 0; i < 2; i++ {
+		s, err := New(path)
+		if err != nil {
+			t.Fatalf("open %d: %v", i+1, err)
+		}
+		if i == 0 {
+			submitted := time.Date(2025, 12, 6, 6, 30, 0, 0, time.UTC)
+			if err := s.SaveSnapshot(&Snapshot{
+				FetchedAt:    oldFetch.Add(24 * time.Hour),
+				NumPages:     1,
+				TotalStories: 1,
+				Stories: []Story{{
+					StoryID: "200", Rank: 1, Headline: "New story", AgeValue: 4, AgeUnit: "hours", Page: 1,
+					SubmittedAt: &submitted,
+				}},
+			}); err != nil {
+				t.Fatalf("SaveSnapshot after migration: %v", err)
+			}
+		}
+		s.Close()
+	}
+
+	s, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	snapshots, err := s.GetSnapshotsInRange(oldFetch.Add(-time.Hour), oldFetch.Add(48*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("got %d snapshots, want 2", len(snapshots))
+	}
+
+	tests := []struct {
+		name      string
+		story     Story
+		headline  string
+		submitted *time.Time
+	}{
+		{"row from before the migration", snapshots[0].Stories[0], "Old story", nil},
+		{"row saved after it", snapshots[1].Stories[0], "New story", ptr(time.Date(2025, 12, 6, 6, 30, 0, 0, time.UTC))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.story.Headline != tt.headline {
+				t.Errorf("headline = %q, want %q", tt.story.Headline, tt.headline)
+			}
+			switch {
+			case tt.submitted == nil && tt.story.SubmittedAt != nil:
+				t.Errorf("submitted at = %v, want none", tt.story.SubmittedAt)
+			case tt.submitted != nil && (tt.story.SubmittedAt == nil || !tt.story.SubmittedAt.Equal(*tt.submitted)):
+				t.Errorf("submitted at = %v, want %v", tt.story.SubmittedAt, tt.submitted)
+			}
+			if tt.story.SubmittedAtEstimated {
+				t.Error("submitted at marked estimated")
+			}
+		})
+	}
+}
+
+func ptr(t time.Time) *time.Time {
+	return &t
+}